@@ -0,0 +1,302 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/go-redis/redis"
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+	cref "github.com/pip-services3-go/pip-services3-commons-go/refer"
+	cauth "github.com/pip-services3-go/pip-services3-components-go/auth"
+	redisconn "github.com/pip-services3-go/pip-services3-redis-go/connect"
+)
+
+// encryptedPrefix marks credentials encrypted with the master key.
+const encryptedPrefix = "enc:"
+
+/*
+RedisCredentialStore are credential store that keeps credentials in Redis,
+so they can be rotated centrally and picked up by all services.
+
+Credentials are stored as JSON under "<namespace>:<key>" keys. The namespace can be protected
+by Redis ACL rules. When a master key is configured, credentials are encrypted with AES-256-GCM
+using the master key, and only encrypted credentials can be read. The master key must be
+32 random bytes encoded in base64 or hex, for instance generated by "openssl rand -base64 32".
+Passwords are not accepted as master keys.
+The credential key is authenticated with the ciphertext, so encrypted values cannot be moved
+to other keys without failing the decryption.
+
+Configuration parameters:
+
+  - connection(s):
+    - discovery_key:         (optional) a key to retrieve the connection from IDiscovery
+    - host:                  host name or IP address
+    - port:                  port number
+    - uri:                   resource URI or connection string with all parameters in it
+  - credential(s):
+    - username:              user name (currently is not used)
+    - password:              user password
+  - options:
+    - namespace:             prefix of the keys in Redis (default: "credentials")
+    - master_key:            (optional) a 32-byte master key in base64 or hex to encrypt credentials
+    - timeout:               connection timeout in milliseconds (default: 30000)
+    - db_num:                database number in Redis  (default 0)
+    - cluster:               enable redis cluster
+
+References:
+
+- *:discovery:*:*:1.0        (optional) IDiscovery services to resolve connection
+
+Example:
+
+    store := NewRedisCredentialStore()
+    store.Configure(cconf.NewConfigParamsFromTuples(
+      "connection.host", "localhost",
+      "connection.port", 6379,
+      "options.master_key", os.Getenv("CREDENTIALS_MASTER_KEY"),
+    ))
+
+    err = store.Open("123")
+      ...
+
+    err = store.Store("123", "key1", cauth.NewCredentialParamsFromTuples(
+      "username", "jdoe",
+      "password", "pass123",
+    ))
+
+    credential, err := store.Lookup("123", "key1")
+    // Result: username=jdoe;password=pass123
+*/
+type RedisCredentialStore struct {
+	connectionResolver *redisconn.RedisConnectionResolver
+
+	namespace string
+	masterKey string
+	cipher    cipher.AEAD
+
+	client redis.UniversalClient
+}
+
+// NewRedisCredentialStore method are creates a new instance of the credential store.
+func NewRedisCredentialStore() *RedisCredentialStore {
+	c := &RedisCredentialStore{
+		connectionResolver: redisconn.NewRedisConnectionResolver(),
+		namespace:          "credentials",
+		masterKey:          "",
+		client:             nil,
+	}
+	return c
+}
+
+// Configure method are configures component by passing configuration parameters.
+// Parameters:
+//   - config    configuration parameters to be set.
+func (c *RedisCredentialStore) Configure(config *cconf.ConfigParams) {
+	c.connectionResolver.Configure(config)
+
+	c.namespace = config.GetAsStringWithDefault("options.namespace", c.namespace)
+	c.masterKey = config.GetAsStringWithDefault("options.master_key", c.masterKey)
+}
+
+// SetReferences method are sets references to dependent components.
+// Parameters:
+//   - references 	references to locate the component dependencies.
+func (c *RedisCredentialStore) SetReferences(references cref.IReferences) {
+	c.connectionResolver.ConnectionResolver.SetReferences(references)
+}
+
+// IsOpen method are checks if the component is opened.
+// Returns true if the component has been opened and false otherwise.
+func (c *RedisCredentialStore) IsOpen() bool {
+	return c.client != nil
+}
+
+// Open method are opens the component.
+// Parameters:
+// 	- correlationId 	(optional) transaction id to trace execution through call chain.
+// Returns: error or nil no errors occured.
+func (c *RedisCredentialStore) Open(correlationId string) error {
+	// The key is checked before connecting, so a wrong key is reported as a configuration error
+	gcm, err := c.newCipher(correlationId)
+	if err != nil {
+		return err
+	}
+
+	client, err := c.connectionResolver.Connect(correlationId)
+	if err != nil {
+		return err
+	}
+
+	c.cipher = gcm
+	c.client = client
+	return nil
+}
+
+// Close method are closes component and frees used resources.
+// Parameters:
+//  - correlationId 	(optional) transaction id to trace execution through call chain.
+// Retruns: error or nil no errors occured.
+func (c *RedisCredentialStore) Close(correlationId string) error {
+	if c.client != nil {
+		err := c.client.Close()
+		c.client = nil
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *RedisCredentialStore) checkOpened(correlationId string) (state bool, err error) {
+	if !c.IsOpen() {
+		err = cerr.NewInvalidStateError(correlationId, "NOT_OPENED", "Connection is not opened")
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Store method are stores credential parameters into the store.
+// A nil credential removes the stored one.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - key               a key to uniquely identify the credential parameters.
+//  - credential        a credential parameters to be stored.
+// Returns: error or nil for success.
+func (c *RedisCredentialStore) Store(correlationId string, key string, credential *cauth.CredentialParams) error {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return err
+	}
+
+	if credential == nil {
+		return c.client.Del(c.namespace + ":" + key).Err()
+	}
+
+	value, err := json.Marshal(credential.Value())
+	if err != nil {
+		return err
+	}
+
+	if c.cipher != nil {
+		value, err = c.encrypt(key, value)
+		if err != nil {
+			return cerr.NewInternalError(correlationId, "ENCRYPT_FAILED", "Failed to encrypt credential").
+				WithDetails("key", key).WithCause(err)
+		}
+	}
+
+	return c.client.Set(c.namespace+":"+key, value, 0).Err()
+}
+
+// Lookup method are lookups credential parameters by its key.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - key               a key to uniquely identify the credential parameters.
+// Returns: found credential, nil if nothing was found, or error.
+func (c *RedisCredentialStore) Lookup(correlationId string, key string) (result *cauth.CredentialParams, err error) {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return nil, err
+	}
+
+	value, err := c.client.Get(c.namespace + ":" + key).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted := strings.HasPrefix(string(value), encryptedPrefix)
+	if c.cipher != nil && !encrypted {
+		return nil, cerr.NewConfigError(correlationId, "NOT_ENCRYPTED", "Credential "+key+" is not encrypted").
+			WithDetails("key", key)
+	}
+	if c.cipher == nil && encrypted {
+		return nil, cerr.NewConfigError(correlationId, "NO_MASTER_KEY", "Master key is required to read credential "+key).
+			WithDetails("key", key)
+	}
+
+	if encrypted {
+		value, err = c.decrypt(key, value)
+		if err != nil {
+			return nil, cerr.NewInternalError(correlationId, "DECRYPT_FAILED", "Failed to decrypt credential").
+				WithDetails("key", key).WithCause(err)
+		}
+	}
+
+	var values map[string]string
+	if err = json.Unmarshal(value, &values); err != nil {
+		return nil, err
+	}
+	return cauth.NewCredentialParams(values), nil
+}
+
+// newCipher creates a cipher with the configured master key, or returns nil when the key is not set.
+func (c *RedisCredentialStore) newCipher(correlationId string) (cipher.AEAD, error) {
+	if c.masterKey == "" {
+		return nil, nil
+	}
+
+	key, err := decodeMasterKey(c.masterKey)
+	if err != nil {
+		return nil, cerr.NewConfigError(correlationId, "INVALID_MASTER_KEY",
+			"Master key must be 32 random bytes encoded in base64 or hex")
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// decodeMasterKey decodes a 32-byte master key from hex or base64.
+func decodeMasterKey(value string) ([]byte, error) {
+	key, err := hex.DecodeString(value)
+	if err != nil {
+		key, err = base64.StdEncoding.DecodeString(value)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != 32 {
+		return nil, aes.KeySizeError(len(key))
+	}
+	return key, nil
+}
+
+// encrypt seals the value with a random nonce and returns "enc:<base64 of nonce and ciphertext>".
+// The credential key is passed as additional authenticated data.
+func (c *RedisCredentialStore) encrypt(key string, value []byte) ([]byte, error) {
+	gcm := c.cipher
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, value, []byte(key))
+	return []byte(encryptedPrefix + base64.StdEncoding.EncodeToString(sealed)), nil
+}
+
+func (c *RedisCredentialStore) decrypt(key string, value []byte) ([]byte, error) {
+	gcm := c.cipher
+	sealed, err := base64.StdEncoding.DecodeString(string(value[len(encryptedPrefix):]))
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	nonce, sealed := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, sealed, []byte(key))
+}
@@ -3,8 +3,14 @@ package build
 import (
 	cref "github.com/pip-services3-go/pip-services3-commons-go/refer"
 	cbuild "github.com/pip-services3-go/pip-services3-components-go/build"
+	redisauth "github.com/pip-services3-go/pip-services3-redis-go/auth"
 	rediscache "github.com/pip-services3-go/pip-services3-redis-go/cache"
+	redisconfig "github.com/pip-services3-go/pip-services3-redis-go/config"
+	redisconn "github.com/pip-services3-go/pip-services3-redis-go/connect"
+	rediscount "github.com/pip-services3-go/pip-services3-redis-go/count"
 	redislock "github.com/pip-services3-go/pip-services3-redis-go/lock"
+	redisqueues "github.com/pip-services3-go/pip-services3-redis-go/queues"
+	redisratelimit "github.com/pip-services3-go/pip-services3-redis-go/ratelimit"
 )
 
 /*
@@ -12,12 +18,36 @@ DefaultRedisFactory are creates Redis components by their descriptors.
 
 See RedisCache
 See RedisLock
+See RedlockLock
+See RedisReadWriteLock
+See RedisSemaphore
+See RedisLeaderElection
+See RedisRateLimiter
+See RedisMessageQueue
+See RedisStreamMessageQueue
+See RedisPubSub
+See RedisCounters
+See RedisDiscovery
+See RedisCredentialStore
+See RedisConfigReader
 */
 type DefaultRedisFactory struct {
 	*cbuild.Factory
-	Descriptor           *cref.Descriptor
-	RedisCacheDescriptor *cref.Descriptor
-	RedisLockDescriptor  *cref.Descriptor
+	Descriptor                        *cref.Descriptor
+	RedisCacheDescriptor              *cref.Descriptor
+	RedisLockDescriptor               *cref.Descriptor
+	RedlockLockDescriptor             *cref.Descriptor
+	RedisReadWriteLockDescriptor      *cref.Descriptor
+	RedisSemaphoreDescriptor          *cref.Descriptor
+	RedisLeaderElectionDescriptor     *cref.Descriptor
+	RedisMessageQueueDescriptor       *cref.Descriptor
+	RedisStreamMessageQueueDescriptor *cref.Descriptor
+	RedisPubSubDescriptor             *cref.Descriptor
+	RedisCountersDescriptor           *cref.Descriptor
+	RedisRateLimiterDescriptor        *cref.Descriptor
+	RedisDiscoveryDescriptor          *cref.Descriptor
+	RedisCredentialStoreDescriptor    *cref.Descriptor
+	RedisConfigReaderDescriptor       *cref.Descriptor
 }
 
 // NewDefaultRedisFactory method are create a new instance of the factory.
@@ -28,7 +58,45 @@ func NewDefaultRedisFactory() *DefaultRedisFactory {
 	c.Descriptor = cref.NewDescriptor("pip-services", "factory", "redis", "default", "1.0")
 	c.RedisCacheDescriptor = cref.NewDescriptor("pip-services", "cache", "redis", "*", "1.0")
 	c.RedisLockDescriptor = cref.NewDescriptor("pip-services", "lock", "redis", "*", "1.0")
+	c.RedlockLockDescriptor = cref.NewDescriptor("pip-services", "lock", "redlock", "*", "1.0")
+	c.RedisReadWriteLockDescriptor = cref.NewDescriptor("pip-services", "read-write-lock", "redis", "*", "1.0")
+	c.RedisSemaphoreDescriptor = cref.NewDescriptor("pip-services", "semaphore", "redis", "*", "1.0")
+	c.RedisLeaderElectionDescriptor = cref.NewDescriptor("pip-services", "leader-election", "redis", "*", "1.0")
+	c.RedisMessageQueueDescriptor = cref.NewDescriptor("pip-services", "message-queue", "redis", "*", "1.0")
+	c.RedisStreamMessageQueueDescriptor = cref.NewDescriptor("pip-services", "message-queue", "redis-stream", "*", "1.0")
+	c.RedisPubSubDescriptor = cref.NewDescriptor("pip-services", "pubsub", "redis", "*", "1.0")
+	c.RedisCountersDescriptor = cref.NewDescriptor("pip-services", "counters", "redis", "*", "1.0")
+	c.RedisRateLimiterDescriptor = cref.NewDescriptor("pip-services", "rate-limiter", "redis", "*", "1.0")
+	c.RedisDiscoveryDescriptor = cref.NewDescriptor("pip-services", "discovery", "redis", "*", "1.0")
+	c.RedisCredentialStoreDescriptor = cref.NewDescriptor("pip-services", "credential-store", "redis", "*", "1.0")
+	c.RedisConfigReaderDescriptor = cref.NewDescriptor("pip-services", "config-reader", "redis", "*", "1.0")
 	c.RegisterType(c.RedisCacheDescriptor, rediscache.NewRedisCache)
 	c.RegisterType(c.RedisLockDescriptor, redislock.NewRedisLock)
+	c.RegisterType(c.RedlockLockDescriptor, redislock.NewRedlockLock)
+	c.RegisterType(c.RedisReadWriteLockDescriptor, redislock.NewRedisReadWriteLock)
+	c.RegisterType(c.RedisSemaphoreDescriptor, redislock.NewRedisSemaphore)
+	c.RegisterType(c.RedisLeaderElectionDescriptor, redislock.NewRedisLeaderElection)
+	c.RegisterType(c.RedisPubSubDescriptor, redisqueues.NewRedisPubSub)
+	c.RegisterType(c.RedisCountersDescriptor, rediscount.NewRedisCounters)
+	c.RegisterType(c.RedisRateLimiterDescriptor, redisratelimit.NewRedisRateLimiter)
+	c.RegisterType(c.RedisDiscoveryDescriptor, redisconn.NewRedisDiscovery)
+	c.RegisterType(c.RedisCredentialStoreDescriptor, redisauth.NewRedisCredentialStore)
+	c.RegisterType(c.RedisConfigReaderDescriptor, redisconfig.NewRedisConfigReader)
+	c.Register(c.RedisMessageQueueDescriptor, func(locator interface{}) interface{} {
+		name := ""
+		descriptor, ok := locator.(*cref.Descriptor)
+		if ok {
+			name = descriptor.Name()
+		}
+		return redisqueues.NewRedisMessageQueue(name)
+	})
+	c.Register(c.RedisStreamMessageQueueDescriptor, func(locator interface{}) interface{} {
+		name := ""
+		descriptor, ok := locator.(*cref.Descriptor)
+		if ok {
+			name = descriptor.Name()
+		}
+		return redisqueues.NewRedisStreamMessageQueue(name)
+	})
 	return &c
 }
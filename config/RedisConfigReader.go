@@ -0,0 +1,332 @@
+package config
+
+import (
+	"sync"
+
+	"github.com/go-redis/redis"
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cconv "github.com/pip-services3-go/pip-services3-commons-go/convert"
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+	cref "github.com/pip-services3-go/pip-services3-commons-go/refer"
+	crun "github.com/pip-services3-go/pip-services3-commons-go/run"
+	cconfig "github.com/pip-services3-go/pip-services3-components-go/config"
+	clog "github.com/pip-services3-go/pip-services3-components-go/log"
+	redisconn "github.com/pip-services3-go/pip-services3-redis-go/connect"
+)
+
+const (
+	// HashFormat keeps every configuration parameter in a separate hash field.
+	HashFormat = "hash"
+	// JsonFormat keeps configuration as a JSON document in a string key.
+	JsonFormat = "json"
+)
+
+/*
+RedisConfigReader are config reader that reads configuration from Redis,
+so it can be changed for all services without redeploying them.
+
+Configuration is kept either in a hash with a field per parameter, like "options.timeout",
+or in a JSON document. Values are parameterized using Mustache template engine.
+
+After configuration is changed with WriteConfig, a notification is published
+to "<key>:changed" channel and all readers notify their change listeners,
+which may read configuration again and reconfigure their components.
+The listeners are called with "key" argument set to the configuration key.
+Only WriteConfig publishes the notifications: configuration edited in Redis directly,
+for instance with redis-cli, is not announced until it is written again with WriteConfig
+or a message is published to "<key>:changed" channel manually.
+
+Configuration parameters:
+
+  - connection(s):
+    - discovery_key:         (optional) a key to retrieve the connection from IDiscovery
+    - host:                  host name or IP address
+    - port:                  port number
+    - uri:                   resource URI or connection string with all parameters in it
+  - credential(s):
+    - store_key:             key to retrieve parameters from credential store
+    - username:              user name (currently is not used)
+    - password:              user password
+  - parameters:              this entire section is used as template parameters
+  - options:
+    - key:                   key of configuration in Redis (default: "config")
+    - format:                format of configuration: hash or json (default: hash)
+    - timeout:               connection timeout in milliseconds (default: 30000)
+    - db_num:                database number in Redis  (default 0)
+    - cluster:               enable redis cluster
+
+References:
+
+- *:logger:*:*:1.0           (optional) ILogger components to pass log messages
+- *:discovery:*:*:1.0        (optional) IDiscovery services to resolve connection
+- *:credential-store:*:*:1.0 (optional) Credential stores to resolve credential
+
+Example:
+
+    ======== config:myservice hash ======
+    options.timeout = "{{TIMEOUT}}"
+    options.retries = "3"
+    =====================================
+
+    reader := NewRedisConfigReader()
+    reader.Configure(cconf.NewConfigParamsFromTuples(
+      "connection.host", "localhost",
+      "connection.port", 6379,
+      "options.key", "config:myservice",
+    ))
+
+    err = reader.Open("123")
+      ...
+
+    reader.AddChangeListener(mycomponent)
+
+    parameters := cconf.NewConfigParamsFromTuples("TIMEOUT", 5000)
+    config, err := reader.ReadConfig("123", parameters)
+    // Result: options.timeout=5000;options.retries=3
+*/
+type RedisConfigReader struct {
+	cconfig.ConfigReader
+
+	connectionResolver *redisconn.RedisConnectionResolver
+	logger             *clog.CompositeLogger
+
+	key    string
+	format string
+
+	listeners   []crun.INotifiable
+	listenersMx sync.Mutex
+
+	pubsub *redis.PubSub
+	client redis.UniversalClient
+}
+
+// NewRedisConfigReader method are creates a new instance of the config reader.
+func NewRedisConfigReader() *RedisConfigReader {
+	c := &RedisConfigReader{
+		ConfigReader:       *cconfig.NewConfigReader(),
+		connectionResolver: redisconn.NewRedisConnectionResolver(),
+		logger:             clog.NewCompositeLogger(),
+		key:                "config",
+		format:             HashFormat,
+		listeners:          []crun.INotifiable{},
+		client:             nil,
+	}
+	return c
+}
+
+// Configure method are configures component by passing configuration parameters.
+// Parameters:
+//   - config    configuration parameters to be set.
+func (c *RedisConfigReader) Configure(config *cconf.ConfigParams) {
+	c.ConfigReader.Configure(config)
+	c.connectionResolver.Configure(config)
+	c.logger.Configure(config)
+
+	c.key = config.GetAsStringWithDefault("options.key", c.key)
+	c.format = config.GetAsStringWithDefault("options.format", c.format)
+}
+
+// SetReferences method are sets references to dependent components.
+// Parameters:
+//   - references 	references to locate the component dependencies.
+func (c *RedisConfigReader) SetReferences(references cref.IReferences) {
+	c.connectionResolver.SetReferences(references)
+	c.logger.SetReferences(references)
+}
+
+// IsOpen method are checks if the component is opened.
+// Returns true if the component has been opened and false otherwise.
+func (c *RedisConfigReader) IsOpen() bool {
+	return c.client != nil
+}
+
+// Open method are opens the component and subscribes to configuration changes.
+// Parameters:
+// 	- correlationId 	(optional) transaction id to trace execution through call chain.
+// Returns: error or nil no errors occured.
+func (c *RedisConfigReader) Open(correlationId string) error {
+	if c.format != HashFormat && c.format != JsonFormat {
+		err := cerr.NewConfigError(
+			correlationId,
+			"WRONG_FORMAT",
+			"Configuration format "+c.format+" is not supported",
+		).WithDetails("format", c.format)
+		return err
+	}
+
+	client, err := c.connectionResolver.Connect(correlationId)
+	if err != nil {
+		return err
+	}
+
+	// The subscriber reconnects and resubscribes when the connection is lost
+	pubsub := client.Subscribe(c.changesChannel())
+	if _, err = pubsub.Receive(); err != nil {
+		pubsub.Close()
+		client.Close()
+		return err
+	}
+
+	c.client = client
+	c.pubsub = pubsub
+	go c.dispatch(pubsub.Channel())
+	return nil
+}
+
+// Close method are closes component and frees used resources.
+// Parameters:
+//  - correlationId 	(optional) transaction id to trace execution through call chain.
+// Retruns: error or nil no errors occured.
+func (c *RedisConfigReader) Close(correlationId string) error {
+	if c.client != nil {
+		c.pubsub.Close()
+		c.pubsub = nil
+
+		err := c.client.Close()
+		c.client = nil
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *RedisConfigReader) checkOpened(correlationId string) (state bool, err error) {
+	if !c.IsOpen() {
+		err = cerr.NewInvalidStateError(correlationId, "NOT_OPENED", "Connection is not opened")
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (c *RedisConfigReader) changesChannel() string {
+	return c.key + ":changed"
+}
+
+// ReadConfig method are reads configuration from Redis and parameterizes it with given values.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - parameters        values to parameters the configuration or nil to skip parameterization.
+// Returns: configuration parameters or error.
+func (c *RedisConfigReader) ReadConfig(correlationId string, parameters *cconf.ConfigParams) (result *cconf.ConfigParams, err error) {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return nil, err
+	}
+
+	if c.format == JsonFormat {
+		data, err := c.client.Get(c.key).Result()
+		if err == redis.Nil {
+			return cconf.NewEmptyConfigParams(), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if parameters != nil {
+			data, err = c.Parameterize(data, parameters)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		value, err := cconv.FromJson(data)
+		if err != nil {
+			err = cerr.NewConfigError(correlationId, "READ_FAILED", "Failed reading configuration "+c.key).
+				WithDetails("key", c.key).WithCause(err)
+			return nil, err
+		}
+		return cconf.NewConfigParamsFromValue(value), nil
+	}
+
+	fields, err := c.client.HGetAll(c.key).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	if parameters != nil {
+		for field, value := range fields {
+			fields[field], err = c.Parameterize(value, parameters)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return cconf.NewConfigParams(fields), nil
+}
+
+// WriteConfig method are replaces configuration in Redis and notifies all readers about the change.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - config            configuration parameters to be written.
+// Returns: error or nil for success.
+func (c *RedisConfigReader) WriteConfig(correlationId string, config *cconf.ConfigParams) error {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return err
+	}
+
+	pipe := c.client.TxPipeline()
+	pipe.Del(c.key)
+	if c.format == JsonFormat {
+		data, err := cconv.ToJson(config.Value())
+		if err != nil {
+			return err
+		}
+		pipe.Set(c.key, data, 0)
+	} else if config.Len() > 0 {
+		fields := map[string]interface{}{}
+		for key, value := range config.Value() {
+			fields[key] = value
+		}
+		pipe.HMSet(c.key, fields)
+	}
+	pipe.Publish(c.changesChannel(), correlationId)
+
+	_, err = pipe.Exec()
+	return err
+}
+
+// AddChangeListener method are adds a listener that will be notified when configuration is changed.
+// Parameters:
+//  - listener          a component to be notified.
+func (c *RedisConfigReader) AddChangeListener(listener crun.INotifiable) {
+	c.listenersMx.Lock()
+	defer c.listenersMx.Unlock()
+
+	c.listeners = append(c.listeners, listener)
+}
+
+// RemoveChangeListener method are removes a previously added change listener.
+// Parameters:
+//  - listener          a component to be removed.
+func (c *RedisConfigReader) RemoveChangeListener(listener crun.INotifiable) {
+	c.listenersMx.Lock()
+	defer c.listenersMx.Unlock()
+
+	for index, registered := range c.listeners {
+		if registered == listener {
+			c.listeners = append(c.listeners[:index], c.listeners[index+1:]...)
+			break
+		}
+	}
+}
+
+// dispatch notifies change listeners about received notifications until the subscriber is closed.
+// The notification payload is a correlation id of the change.
+func (c *RedisConfigReader) dispatch(messages <-chan *redis.Message) {
+	for message := range messages {
+		c.logger.Debug(message.Payload, "Configuration %s was changed", c.key)
+
+		c.listenersMx.Lock()
+		listeners := make([]crun.INotifiable, len(c.listeners))
+		copy(listeners, c.listeners)
+		c.listenersMx.Unlock()
+
+		args := crun.NewParametersFromTuples("key", c.key)
+		for _, listener := range listeners {
+			listener.Notify(message.Payload, args)
+		}
+	}
+}
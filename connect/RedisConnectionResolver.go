@@ -0,0 +1,162 @@
+package connect
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+	cref "github.com/pip-services3-go/pip-services3-commons-go/refer"
+	cauth "github.com/pip-services3-go/pip-services3-components-go/auth"
+	ccon "github.com/pip-services3-go/pip-services3-components-go/connect"
+)
+
+/*
+RedisConnectionResolver are helper class that resolves Redis connection and credential parameters,
+and creates clients of go-redis driver for them.
+
+Configuration parameters:
+
+  - connection(s):
+    - discovery_key:         (optional) a key to retrieve the connection from IDiscovery
+    - host:                  host name or IP address
+    - port:                  port number
+    - uri:                   resource URI or connection string with all parameters in it, e.g. "redis://:password@localhost:6379/1"
+  - credential(s):
+    - store_key:             key to retrieve parameters from credential store
+    - username:              user name (currently is not used)
+    - password:              user password
+  - options:
+    - timeout:               connection timeout in milliseconds (default: 30000)
+    - db_num:                database number in Redis, when it is not set in the uri (default 0)
+    - cluster:               enable redis cluster
+
+References:
+
+- *:discovery:*:*:1.0        (optional) IDiscovery services to resolve connection
+- *:credential-store:*:*:1.0 (optional) Credential stores to resolve credential
+*/
+type RedisConnectionResolver struct {
+	ConnectionResolver *ccon.ConnectionResolver
+	CredentialResolver *cauth.CredentialResolver
+
+	timeout   int
+	dbNum     int
+	isCluster bool
+}
+
+// NewRedisConnectionResolver method are creates a new instance of the connection resolver.
+func NewRedisConnectionResolver() *RedisConnectionResolver {
+	c := &RedisConnectionResolver{
+		ConnectionResolver: ccon.NewEmptyConnectionResolver(),
+		CredentialResolver: cauth.NewEmptyCredentialResolver(),
+		timeout:            30000,
+		dbNum:              0,
+	}
+	return c
+}
+
+// Configure method are configures component by passing configuration parameters.
+// Parameters:
+//   - config    configuration parameters to be set.
+func (c *RedisConnectionResolver) Configure(config *cconf.ConfigParams) {
+	c.ConnectionResolver.Configure(config)
+	c.CredentialResolver.Configure(config)
+
+	c.timeout = config.GetAsIntegerWithDefault("options.timeout", c.timeout)
+	c.dbNum = config.GetAsIntegerWithDefault("options.db_num", c.dbNum)
+	if c.dbNum > 15 || c.dbNum < 0 {
+		c.dbNum = 0
+	}
+	c.isCluster = config.GetAsBooleanWithDefault("options.cluster", c.isCluster)
+}
+
+// SetReferences method are sets references to dependent components.
+// Parameters:
+//   - references 	references to locate the component dependencies.
+func (c *RedisConnectionResolver) SetReferences(references cref.IReferences) {
+	c.ConnectionResolver.SetReferences(references)
+	c.CredentialResolver.SetReferences(references)
+}
+
+// Resolve method are resolves client options from connection and credential parameters.
+// Parameters:
+//  - correlationId 	(optional) transaction id to trace execution through call chain.
+// Returns: resolved client options or error.
+func (c *RedisConnectionResolver) Resolve(correlationId string) (options *redis.Options, err error) {
+	connection, err := c.ConnectionResolver.Resolve(correlationId)
+	if err != nil {
+		return nil, err
+	}
+	if connection == nil {
+		err = cerr.NewConfigError(correlationId, "NO_CONNECTION", "Connection is not configured")
+		return nil, err
+	}
+
+	credential, err := c.CredentialResolver.Lookup(correlationId)
+	if err != nil {
+		return nil, err
+	}
+
+	if connection.Uri() != "" {
+		// Connection strings carry the address, password, database and TLS settings
+		options, err = redis.ParseURL(connection.Uri())
+		if err != nil {
+			err = cerr.NewConfigError(correlationId, "WRONG_URI", "Connection uri is invalid").
+				WithDetails("uri", connection.Uri()).WithCause(err)
+			return nil, err
+		}
+		if options.DB == 0 {
+			options.DB = c.dbNum
+		}
+	} else {
+		host := connection.Host()
+		if host == "" {
+			host = "localhost"
+		}
+		port := strconv.FormatInt(int64(connection.Port()), 10)
+		if port == "0" {
+			port = "6379"
+		}
+		options = &redis.Options{
+			Addr: host + ":" + port,
+			DB:   c.dbNum,
+		}
+	}
+
+	options.DialTimeout = time.Duration(c.timeout) * time.Millisecond
+	if credential != nil && credential.Password() != "" {
+		options.Password = credential.Password()
+	}
+	return options, nil
+}
+
+// Connect method are resolves connection parameters and opens a client connected to Redis.
+// Parameters:
+//  - correlationId 	(optional) transaction id to trace execution through call chain.
+// Returns: connected client or error.
+func (c *RedisConnectionResolver) Connect(correlationId string) (client redis.UniversalClient, err error) {
+	options, err := c.Resolve(correlationId)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.isCluster {
+		client = redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:       []string{options.Addr},
+			Password:    options.Password,
+			DialTimeout: options.DialTimeout,
+			TLSConfig:   options.TLSConfig,
+		})
+	} else {
+		client = redis.NewClient(options)
+	}
+
+	if err = client.Ping().Err(); err != nil {
+		client.Close()
+		err = cerr.NewConnectionError(correlationId, "CONNECT_FAILED", "Connection to Redis failed").WithCause(err)
+		return nil, err
+	}
+	return client, nil
+}
@@ -0,0 +1,334 @@
+package connect
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+	cref "github.com/pip-services3-go/pip-services3-commons-go/refer"
+	ccon "github.com/pip-services3-go/pip-services3-components-go/connect"
+	clog "github.com/pip-services3-go/pip-services3-components-go/log"
+)
+
+/*
+RedisDiscovery are discovery service that keeps connection parameters in Redis,
+so services can register themselves and find their peers.
+
+Registrations are kept in "discovery:<key>" sorted sets scored by their expiration time.
+Connections registered by this component are renewed by heartbeats until it is closed,
+so registrations of crashed services expire on their own.
+
+Configuration parameters:
+
+  - connection(s):
+    - host:                  host name or IP address
+    - port:                  port number
+    - uri:                   resource URI or connection string with all parameters in it
+  - credential(s):
+    - store_key:             key to retrieve parameters from credential store
+    - username:              user name (currently is not used)
+    - password:              user password
+  - options:
+    - ttl:                   time to live of registrations in milliseconds (default: 30000)
+    - heartbeat_interval:    interval in milliseconds to renew registrations (default: ttl / 3)
+    - timeout:               connection timeout in milliseconds (default: 30000)
+    - db_num:                database number in Redis  (default 0)
+    - cluster:               enable redis cluster
+
+References:
+
+- *:logger:*:*:1.0           (optional) ILogger components to pass log messages
+- *:credential-store:*:*:1.0 (optional) Credential stores to resolve credential
+
+Example:
+
+    discovery := NewRedisDiscovery()
+    discovery.Configure(cconf.NewConfigParamsFromTuples(
+      "connection.host", "localhost",
+      "connection.port", 6379,
+    ))
+
+    err = discovery.Open("123")
+      ...
+
+    discovery.Register("123", "mycontroller", ccon.NewConnectionParamsFromTuples(
+      "host", "10.1.1.100",
+      "port", 8080,
+    ))
+
+    connection, err := discovery.ResolveOne("123", "mycontroller")
+    // Result: host=10.1.1.100;port=8080
+*/
+type RedisDiscovery struct {
+	connectionResolver *RedisConnectionResolver
+	logger             *clog.CompositeLogger
+
+	ttl               int64
+	heartbeatInterval int64
+
+	registrations   map[string][]string
+	registrationsMx sync.Mutex
+	stop            chan struct{}
+	heartbeatGroup  sync.WaitGroup
+
+	client redis.UniversalClient
+}
+
+// NewRedisDiscovery method are creates a new instance of the discovery service.
+func NewRedisDiscovery() *RedisDiscovery {
+	c := &RedisDiscovery{
+		connectionResolver: NewRedisConnectionResolver(),
+		logger:             clog.NewCompositeLogger(),
+		ttl:                30000,
+		heartbeatInterval:  0,
+		registrations:      map[string][]string{},
+		client:             nil,
+	}
+	return c
+}
+
+// Configure method are configures component by passing configuration parameters.
+// Parameters:
+//   - config    configuration parameters to be set.
+func (c *RedisDiscovery) Configure(config *cconf.ConfigParams) {
+	c.connectionResolver.Configure(config)
+	c.logger.Configure(config)
+
+	c.ttl = config.GetAsLongWithDefault("options.ttl", c.ttl)
+	c.heartbeatInterval = config.GetAsLongWithDefault("options.heartbeat_interval", c.heartbeatInterval)
+}
+
+// SetReferences method are sets references to dependent components.
+// Parameters:
+//   - references 	references to locate the component dependencies.
+func (c *RedisDiscovery) SetReferences(references cref.IReferences) {
+	c.connectionResolver.SetReferences(references)
+	c.logger.SetReferences(references)
+}
+
+// IsOpen method are checks if the component is opened.
+// Returns true if the component has been opened and false otherwise.
+func (c *RedisDiscovery) IsOpen() bool {
+	return c.client != nil
+}
+
+// Open method are opens the component, registers connections added before
+// and starts renewing them.
+// Parameters:
+// 	- correlationId 	(optional) transaction id to trace execution through call chain.
+// Returns: error or nil no errors occured.
+func (c *RedisDiscovery) Open(correlationId string) error {
+	client, err := c.connectionResolver.Connect(correlationId)
+	if err != nil {
+		return err
+	}
+
+	c.client = client
+	if err = c.renewRegistrations(); err != nil {
+		c.client = nil
+		client.Close()
+		return err
+	}
+
+	c.stop = make(chan struct{})
+	c.heartbeatGroup.Add(1)
+	go c.heartbeat(c.stop)
+	return nil
+}
+
+// Close method are removes registered connections, closes component and frees used resources.
+// Parameters:
+//  - correlationId 	(optional) transaction id to trace execution through call chain.
+// Retruns: error or nil no errors occured.
+func (c *RedisDiscovery) Close(correlationId string) error {
+	if c.client != nil {
+		close(c.stop)
+		// Wait for the heartbeat, so it does not renew registrations after they are removed
+		c.heartbeatGroup.Wait()
+
+		c.registrationsMx.Lock()
+		for key, members := range c.registrations {
+			if err := c.client.ZRem(c.registrationKey(key), toInterfaces(members)...).Err(); err != nil {
+				c.logger.Warn(correlationId, "Failed to remove registrations for %s: %s", key, err.Error())
+			}
+		}
+		c.registrationsMx.Unlock()
+
+		err := c.client.Close()
+		c.client = nil
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *RedisDiscovery) checkOpened(correlationId string) (state bool, err error) {
+	if !c.IsOpen() {
+		err = cerr.NewInvalidStateError(correlationId, "NOT_OPENED", "Connection is not opened")
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (c *RedisDiscovery) registrationKey(key string) string {
+	return "discovery:" + key
+}
+
+// registerScript adds or renews registrations with expiration time taken from the Redis server clock
+// and removes the expired ones.
+var registerScript = redis.NewScript(`
+redis.replicate_commands()
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local ttl = tonumber(ARGV[1])
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now)
+for i = 2, #ARGV do
+	redis.call("ZADD", KEYS[1], now + ttl, ARGV[i])
+end
+if redis.call("PTTL", KEYS[1]) < ttl then
+	redis.call("PEXPIRE", KEYS[1], ttl)
+end
+return 1
+`)
+
+// resolveScript returns registrations that are not expired.
+var resolveScript = redis.NewScript(`
+redis.replicate_commands()
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+return redis.call("ZRANGEBYSCORE", KEYS[1], "(" .. now, "+inf")
+`)
+
+// heartbeat periodically renews registrations made by this component.
+func (c *RedisDiscovery) heartbeat(stop chan struct{}) {
+	defer c.heartbeatGroup.Done()
+
+	interval := c.heartbeatInterval
+	if interval <= 0 {
+		interval = c.ttl / 3
+	}
+
+	ticker := time.NewTicker(time.Duration(interval) * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := c.renewRegistrations(); err != nil {
+				c.logger.Error("", err, "Failed to renew registrations")
+			}
+		}
+	}
+}
+
+func (c *RedisDiscovery) renewRegistrations() error {
+	c.registrationsMx.Lock()
+	defer c.registrationsMx.Unlock()
+
+	for key, members := range c.registrations {
+		args := append([]interface{}{c.ttl}, toInterfaces(members)...)
+		if err := registerScript.Run(c.client, []string{c.registrationKey(key)}, args...).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Register method are registers connection parameters into the discovery service.
+// The registration is renewed until the component is closed.
+// Registrations made before the component is opened are sent to Redis on open.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - key               a key to uniquely identify the connection parameters.
+//  - connection        a connection to be registered.
+// Returns: registered connection or error.
+func (c *RedisDiscovery) Register(correlationId string, key string,
+	connection *ccon.ConnectionParams) (result *ccon.ConnectionParams, err error) {
+	if connection == nil {
+		return nil, nil
+	}
+
+	// Maps are serialized with sorted keys, so equal connections are registered once
+	buffer, err := json.Marshal(connection.Value())
+	if err != nil {
+		return nil, err
+	}
+	member := string(buffer)
+
+	c.registrationsMx.Lock()
+	defer c.registrationsMx.Unlock()
+
+	if c.client != nil {
+		err = registerScript.Run(c.client, []string{c.registrationKey(key)}, c.ttl, member).Err()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, registered := range c.registrations[key] {
+		if registered == member {
+			return connection, nil
+		}
+	}
+	c.registrations[key] = append(c.registrations[key], member)
+	return connection, nil
+}
+
+// ResolveOne method are resolves a single connection parameters by its key.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - key               a key to uniquely identify the connection.
+// Returns: found connection, nil if nothing was found, or error.
+func (c *RedisDiscovery) ResolveOne(correlationId string, key string) (result *ccon.ConnectionParams, err error) {
+	connections, err := c.ResolveAll(correlationId, key)
+	if err != nil || len(connections) == 0 {
+		return nil, err
+	}
+	return connections[0], nil
+}
+
+// ResolveAll method are resolves all registered connection parameters by their key.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - key               a key to uniquely identify the connections.
+// Returns: a list of found connections or error.
+func (c *RedisDiscovery) ResolveAll(correlationId string, key string) (result []*ccon.ConnectionParams, err error) {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return nil, err
+	}
+
+	members, err := resolveScript.Run(c.client, []string{c.registrationKey(key)}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	result = []*ccon.ConnectionParams{}
+	values, _ := members.([]interface{})
+	for _, member := range values {
+		text, _ := member.(string)
+
+		var value map[string]string
+		if err := json.Unmarshal([]byte(text), &value); err != nil {
+			c.logger.Warn(correlationId, "Skipped invalid registration for %s: %s", key, text)
+			continue
+		}
+		result = append(result, ccon.NewConnectionParams(value))
+	}
+	return result, nil
+}
+
+func toInterfaces(values []string) []interface{} {
+	result := make([]interface{}, len(values))
+	for index, value := range values {
+		result[index] = value
+	}
+	return result
+}
@@ -0,0 +1,527 @@
+package count
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cconv "github.com/pip-services3-go/pip-services3-commons-go/convert"
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+	cref "github.com/pip-services3-go/pip-services3-commons-go/refer"
+	ccount "github.com/pip-services3-go/pip-services3-components-go/count"
+	clog "github.com/pip-services3-go/pip-services3-components-go/log"
+	redisconn "github.com/pip-services3-go/pip-services3-redis-go/connect"
+)
+
+/*
+RedisCounters are performance counters that aggregate measurements from all service instances in Redis.
+
+Counters are measured in memory and periodically flushed into Redis hashes.
+Every flush adds the changes since the previous flush, so the hashes keep
+cluster-wide totals:
+  - <prefix>:total              a hash of counters measured since they were created
+  - <prefix>:<interval start>   a hash of counters measured within the time interval,
+                                where the interval start is a time in milliseconds
+
+Counters that were reset or cleared in memory are recreated, so they are flushed from the start.
+Sums of measured values are accumulated separately with double precision,
+since averages kept by the counters in memory lose precision on large counts.
+Interval hashes only get counters that changed within the interval. Their min and max values
+are set only when a new minimum or maximum was measured within the interval,
+since the counters in memory keep their min and max values since they were created.
+
+Every counter is stored in hash fields "<name>:type", "<name>:count", "<name>:sum",
+"<name>:min", "<name>:max", "<name>:last" and "<name>:time".
+
+Configuration parameters:
+
+  - connection(s):
+    - discovery_key:             (optional) a key to retrieve the connection from IDiscovery
+    - host:                      host name or IP address
+    - port:                      port number
+    - uri:                       resource URI or connection string with all parameters in it
+  - credential(s):
+    - store_key:                 key to retrieve parameters from credential store
+    - username:                  user name (currently is not used)
+    - password:                  user password
+  - interval:                    interval in milliseconds to flush current counters measurements (default: 10000)
+  - reset_timeout:               timeout in milliseconds to reset the counters. 0 disables the reset (default: 0)
+  - options:
+    - prefix:                    prefix of the keys in Redis (default: "counters")
+    - bucket_interval:           length of per-interval buckets in milliseconds (default: 60000)
+    - bucket_timeout:            time in milliseconds the buckets are kept in Redis (default: 86400000)
+    - timeout:                   connection timeout in milliseconds (default: 30000)
+    - db_num:                    database number in Redis  (default 0)
+    - cluster:                   enable redis cluster
+
+References:
+
+- *:logger:*:*:1.0           (optional) ILogger components to pass log messages
+- *:discovery:*:*:1.0        (optional) IDiscovery services to resolve connection
+- *:credential-store:*:*:1.0 (optional) Credential stores to resolve credential
+
+Example:
+
+    counters := NewRedisCounters()
+    counters.Configure(cconf.NewConfigParamsFromTuples(
+      "connection.host", "localhost",
+      "connection.port", 6379,
+    ))
+
+    err = counters.Open("123")
+      ...
+
+    counters.IncrementOne("mycomponent.mymethod.calls")
+    timing := counters.BeginTiming("mycomponent.mymethod.exec_time")
+    defer timing.EndTiming()
+
+    // Reading cluster-wide totals
+    totals, err := counters.ReadCounters("123")
+*/
+type RedisCounters struct {
+	*ccount.CachedCounters
+
+	connectionResolver *redisconn.RedisConnectionResolver
+	logger             *clog.CompositeLogger
+
+	interval       int64
+	prefix         string
+	bucketInterval int64
+	bucketTimeout  int64
+
+	flushed    map[string]*flushedCounter
+	flushedMx  sync.Mutex
+	sums       map[string]*measuredSum
+	sumsMx     sync.Mutex
+	stop       chan struct{}
+	flushGroup sync.WaitGroup
+
+	client redis.UniversalClient
+}
+
+// flushedCounter keeps counter values sent to Redis, so only their changes are flushed next time.
+// The counter itself is kept to detect resets, that replace counters in memory with new ones.
+type flushedCounter struct {
+	counter *ccount.Counter
+	count   int
+	sum     float64
+	min     float32
+	max     float32
+	last    float32
+	time    time.Time
+}
+
+// measuredSum keeps a sum of values measured by the counter since it was created.
+type measuredSum struct {
+	counter *ccount.Counter
+	sum     float64
+}
+
+// NewRedisCounters method are creates a new instance of the counters.
+func NewRedisCounters() *RedisCounters {
+	c := &RedisCounters{
+		connectionResolver: redisconn.NewRedisConnectionResolver(),
+		logger:             clog.NewCompositeLogger(),
+		interval:           10000,
+		prefix:             "counters",
+		bucketInterval:     60000,
+		bucketTimeout:      86400000,
+		flushed:            map[string]*flushedCounter{},
+		sums:               map[string]*measuredSum{},
+		client:             nil,
+	}
+	c.CachedCounters = ccount.InheritCacheCounters(c)
+	c.CachedCounters.Configure(cconf.NewConfigParamsFromTuples("interval", c.interval))
+	return c
+}
+
+// Configure method are configures component by passing configuration parameters.
+// Parameters:
+//   - config    configuration parameters to be set.
+func (c *RedisCounters) Configure(config *cconf.ConfigParams) {
+	c.CachedCounters.Configure(config)
+	c.connectionResolver.Configure(config)
+	c.logger.Configure(config)
+
+	c.interval = config.GetAsLongWithDefault("interval", c.interval)
+	c.prefix = config.GetAsStringWithDefault("options.prefix", c.prefix)
+	c.bucketInterval = config.GetAsLongWithDefault("options.bucket_interval", c.bucketInterval)
+	c.bucketTimeout = config.GetAsLongWithDefault("options.bucket_timeout", c.bucketTimeout)
+}
+
+// SetReferences method are sets references to dependent components.
+// Parameters:
+//   - references 	references to locate the component dependencies.
+func (c *RedisCounters) SetReferences(references cref.IReferences) {
+	c.connectionResolver.SetReferences(references)
+	c.logger.SetReferences(references)
+}
+
+// IsOpen method are checks if the component is opened.
+// Returns true if the component has been opened and false otherwise.
+func (c *RedisCounters) IsOpen() bool {
+	return c.client != nil
+}
+
+// Open method are opens the component and starts flushing the counters.
+// Parameters:
+// 	- correlationId 	(optional) transaction id to trace execution through call chain.
+// Returns: error or nil no errors occured.
+func (c *RedisCounters) Open(correlationId string) error {
+	client, err := c.connectionResolver.Connect(correlationId)
+	if err != nil {
+		return err
+	}
+
+	c.client = client
+	c.stop = make(chan struct{})
+	c.flushGroup.Add(1)
+	go c.flush(c.stop)
+	return nil
+}
+
+// Close method are flushes the counters, closes component and frees used resources.
+// Parameters:
+//  - correlationId 	(optional) transaction id to trace execution through call chain.
+// Retruns: error or nil no errors occured.
+func (c *RedisCounters) Close(correlationId string) error {
+	if c.client != nil {
+		close(c.stop)
+		// Wait for the flush in progress, so the counters are not saved twice
+		// and the client is not used after it is closed
+		c.flushGroup.Wait()
+		if err := c.Dump(); err != nil {
+			c.logger.Error(correlationId, err, "Failed to flush counters")
+		}
+
+		err := c.client.Close()
+		c.client = nil
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flush periodically dumps the counters, so they are saved even when they are not updated.
+func (c *RedisCounters) flush(stop chan struct{}) {
+	defer c.flushGroup.Done()
+
+	interval := time.Duration(c.interval) * time.Millisecond
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := c.Dump(); err != nil {
+				c.logger.Error("", err, "Failed to flush counters")
+			}
+		}
+	}
+}
+
+// saveCountersScript adds the counter changes to the total and bucket hashes.
+// Every counter is passed as 12 arguments: name, type, count, sum, min, max, last and time
+// for the total hash, followed by min, max, last and time for the bucket hash.
+// Counters that did not change within the interval are not added to the bucket.
+var saveCountersScript = redis.NewScript(`
+local function update(key, name, values)
+	redis.call("HSET", key, name .. ":type", values[1])
+	if values[2] ~= 0 then
+		redis.call("HINCRBY", key, name .. ":count", values[2])
+	end
+	if values[3] ~= 0 then
+		redis.call("HINCRBYFLOAT", key, name .. ":sum", values[3])
+	end
+	local min = tonumber(redis.call("HGET", key, name .. ":min"))
+	if values[4] and (not min or values[4] < min) then
+		redis.call("HSET", key, name .. ":min", values[4])
+	end
+	local max = tonumber(redis.call("HGET", key, name .. ":max"))
+	if values[5] and (not max or values[5] > max) then
+		redis.call("HSET", key, name .. ":max", values[5])
+	end
+	if values[6] then
+		redis.call("HSET", key, name .. ":last", values[6])
+	end
+	local time = tonumber(redis.call("HGET", key, name .. ":time"))
+	if values[7] and (not time or values[7] > time) then
+		redis.call("HSET", key, name .. ":time", values[7])
+	end
+end
+
+for i = 2, #ARGV, 12 do
+	local values = {}
+	for j = 1, 11 do
+		values[j] = tonumber(ARGV[i + j])
+	end
+	update(KEYS[1], ARGV[i], {values[1], values[2], values[3], values[4], values[5], values[6], values[7]})
+	if values[2] ~= 0 or values[3] ~= 0 or values[8] or values[9] or values[10] or values[11] then
+		update(KEYS[2], ARGV[i], {values[1], values[2], values[3], values[8], values[9], values[10], values[11]})
+	end
+end
+redis.call("PEXPIRE", KEYS[2], ARGV[1])
+return 1
+`)
+
+// Save method are saves the changes of the counters measurements since the previous save.
+// Parameters:
+//   - counters  current counters measurements to be saved.
+// Returns: error or nil for success.
+func (c *RedisCounters) Save(counters []*ccount.Counter) error {
+	if len(counters) == 0 || c.client == nil {
+		return nil
+	}
+
+	c.flushedMx.Lock()
+	defer c.flushedMx.Unlock()
+
+	args := []interface{}{c.bucketTimeout}
+	flushed := map[string]*flushedCounter{}
+	for _, counter := range counters {
+		current := &flushedCounter{
+			counter: counter,
+			count:   counter.Count,
+			min:     counter.Min,
+			max:     counter.Max,
+			last:    counter.Last,
+			time:    counter.Time,
+		}
+		current.sum = c.getSum(counter)
+		flushed[counter.Name] = current
+
+		// Counters that were reset are new objects, so they are flushed from the start
+		previous, ok := c.flushed[counter.Name]
+		reset := !ok || previous.counter != counter
+		count, delta := current.count, current.sum
+		if !reset {
+			count -= previous.count
+			delta -= previous.sum
+		}
+
+		var min, max, last, timestamp interface{} = "", "", "", ""
+		var bucketMin, bucketMax, bucketLast, bucketTimestamp interface{} = "", "", "", ""
+		switch counter.Type {
+		case ccount.Increment:
+			delta = 0
+		case ccount.LastValue:
+			last = current.last
+			if reset || current.last != previous.last {
+				bucketLast = last
+			}
+		case ccount.Timestamp:
+			timestamp = current.time.UnixNano() / int64(time.Millisecond)
+			if reset || current.time.After(previous.time) {
+				bucketTimestamp = timestamp
+			}
+		default:
+			last = current.last
+			if count != 0 {
+				bucketLast = last
+			}
+			if current.min != math.MaxFloat32 {
+				min = current.min
+				if reset || current.min < previous.min {
+					bucketMin = min
+				}
+			}
+			if current.max != -math.MaxFloat32 {
+				max = current.max
+				if reset || current.max > previous.max {
+					bucketMax = max
+				}
+			}
+		}
+
+		args = append(args, counter.Name, counter.Type, count, delta, min, max, last, timestamp,
+			bucketMin, bucketMax, bucketLast, bucketTimestamp)
+	}
+
+	keys := []string{c.prefix + ":total", c.bucketKey(time.Now())}
+	if err := saveCountersScript.Run(c.client, keys, args...).Err(); err != nil {
+		return err
+	}
+
+	// Counters are saved all together, so the ones that are missing were cleared or reset
+	c.flushed = flushed
+	return nil
+}
+
+// getSum gets a sum of values measured by the counter.
+func (c *RedisCounters) getSum(counter *ccount.Counter) float64 {
+	c.sumsMx.Lock()
+	defer c.sumsMx.Unlock()
+
+	if measured, ok := c.sums[counter.Name]; ok && measured.counter == counter {
+		return measured.sum
+	}
+	return 0
+}
+
+// addSum adds the measured value to the sum of the counter values.
+// Counters that were reset are new objects, so their sums start from zero.
+func (c *RedisCounters) addSum(counter *ccount.Counter, value float32) {
+	c.sumsMx.Lock()
+	defer c.sumsMx.Unlock()
+
+	measured, ok := c.sums[counter.Name]
+	if !ok || measured.counter != counter {
+		measured = &measuredSum{counter: counter}
+		c.sums[counter.Name] = measured
+	}
+	measured.sum += float64(value)
+}
+
+// BeginTiming method are begins measurement of execution time interval.
+// Parameters:
+//   - name      a counter name of Interval type.
+// Returns: a CounterTiming callback object to end timing.
+func (c *RedisCounters) BeginTiming(name string) *ccount.CounterTiming {
+	return ccount.NewCounterTiming(name, c)
+}
+
+// EndTiming method are ends measurement of execution elapsed time and updates specified counter.
+// Parameters:
+//   - name      a counter name.
+//   - elapsed   execution elapsed time in milliseconds to update the counter.
+func (c *RedisCounters) EndTiming(name string, elapsed float32) {
+	c.addSum(c.Get(name, ccount.Interval), elapsed)
+	c.CachedCounters.EndTiming(name, elapsed)
+}
+
+// Stats method are calculates min/average/max statistics based on the current and previous values.
+// Parameters:
+//   - name      a counter name of Statistics type.
+//   - value     a value to update statistics.
+func (c *RedisCounters) Stats(name string, value float32) {
+	c.addSum(c.Get(name, ccount.Statistics), value)
+	c.CachedCounters.Stats(name, value)
+}
+
+// Clear method are clears (resets) a counter specified by its name.
+// Parameters:
+//   - name      a counter name to clear.
+func (c *RedisCounters) Clear(name string) {
+	c.CachedCounters.Clear(name)
+
+	c.sumsMx.Lock()
+	delete(c.sums, name)
+	c.sumsMx.Unlock()
+
+	c.flushedMx.Lock()
+	delete(c.flushed, name)
+	c.flushedMx.Unlock()
+}
+
+// ClearAll method are clears (resets) all counters.
+func (c *RedisCounters) ClearAll() {
+	c.CachedCounters.ClearAll()
+
+	c.sumsMx.Lock()
+	c.sums = map[string]*measuredSum{}
+	c.sumsMx.Unlock()
+
+	c.flushedMx.Lock()
+	c.flushed = map[string]*flushedCounter{}
+	c.flushedMx.Unlock()
+}
+
+// bucketKey gets a key of the bucket the time falls into.
+func (c *RedisCounters) bucketKey(time time.Time) string {
+	start := time.UnixNano() / 1000000
+	if c.bucketInterval > 0 {
+		start -= start % c.bucketInterval
+	}
+	return c.prefix + ":" + strconv.FormatInt(start, 10)
+}
+
+func (c *RedisCounters) checkOpened(correlationId string) (state bool, err error) {
+	if !c.IsOpen() {
+		err = cerr.NewInvalidStateError(correlationId, "NOT_OPENED", "Connection is not opened")
+		return false, err
+	}
+
+	return true, nil
+}
+
+// ReadCounters method are reads cluster-wide totals of the counters.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+// Returns: a list of counters or error.
+func (c *RedisCounters) ReadCounters(correlationId string) (result []*ccount.Counter, err error) {
+	return c.readCounters(correlationId, c.prefix+":total")
+}
+
+// ReadIntervalCounters method are reads cluster-wide counters measured within the interval the time falls into.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - time              a time within the interval.
+// Returns: a list of counters or error.
+func (c *RedisCounters) ReadIntervalCounters(correlationId string, time time.Time) (result []*ccount.Counter, err error) {
+	return c.readCounters(correlationId, c.bucketKey(time))
+}
+
+func (c *RedisCounters) readCounters(correlationId string, key string) (result []*ccount.Counter, err error) {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return nil, err
+	}
+
+	fields, err := c.client.HGetAll(key).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	counters := map[string]*ccount.Counter{}
+	sums := map[string]float64{}
+	result = []*ccount.Counter{}
+	for field, value := range fields {
+		pos := strings.LastIndex(field, ":")
+		if pos < 0 {
+			continue
+		}
+		name := field[:pos]
+
+		counter, ok := counters[name]
+		if !ok {
+			counter = ccount.NewCounter(name, ccount.Increment)
+			counters[name] = counter
+			result = append(result, counter)
+		}
+
+		switch field[pos+1:] {
+		case "type":
+			counter.Type = cconv.IntegerConverter.ToInteger(value)
+		case "count":
+			counter.Count = cconv.IntegerConverter.ToInteger(value)
+		case "sum":
+			sums[name] = cconv.DoubleConverter.ToDouble(value)
+		case "min":
+			counter.Min = cconv.FloatConverter.ToFloat(value)
+		case "max":
+			counter.Max = cconv.FloatConverter.ToFloat(value)
+		case "last":
+			counter.Last = cconv.FloatConverter.ToFloat(value)
+		case "time":
+			counter.Time = time.Unix(0, cconv.LongConverter.ToLong(value)*int64(time.Millisecond)).UTC()
+		}
+	}
+
+	for _, counter := range result {
+		if counter.Count > 0 && counter.Type != ccount.Increment {
+			counter.Average = float32(sums[counter.Name] / float64(counter.Count))
+		}
+	}
+	return result, nil
+}
@@ -9,5 +9,6 @@ require (
 	github.com/onsi/gomega v1.24.2 // indirect
 	github.com/pip-services3-go/pip-services3-commons-go v1.1.6
 	github.com/pip-services3-go/pip-services3-components-go v1.3.2
+	github.com/pip-services3-go/pip-services3-messaging-go v1.1.0
 	github.com/stretchr/testify v1.8.1
 )
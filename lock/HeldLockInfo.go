@@ -0,0 +1,23 @@
+package lock
+
+import "time"
+
+/*
+HeldLockInfo are information about a lock held by RedisLock component.
+
+See RedisLock
+*/
+type HeldLockInfo struct {
+	// Key is a unique lock key.
+	Key string `json:"key"`
+	// Token is a token of the lock holder.
+	Token string `json:"token"`
+	// CorrelationId is a transaction id of the acquisition.
+	CorrelationId string `json:"correlation_id"`
+	// AcquiredAt is a time when the lock was acquired.
+	AcquiredAt time.Time `json:"acquired_at"`
+	// Site is a place in the code that acquired the lock. It is captured only in debug mode.
+	Site string `json:"site"`
+
+	reported bool
+}
@@ -0,0 +1,7 @@
+package lock
+
+// LeadershipCallback are function called when a candidate gains or loses the leadership.
+// Parameters:
+//   - group         a name of the leader election group.
+//   - candidateId   an id of the candidate that was elected or revoked.
+type LeadershipCallback func(group string, candidateId string)
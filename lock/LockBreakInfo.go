@@ -0,0 +1,28 @@
+package lock
+
+import "time"
+
+/*
+LockBreakInfo are audit record about a lock that was forcibly released.
+The same record is published to the previous owner on "lock:lost" channel.
+
+See RedisLock.ForceReleaseLock
+*/
+type LockBreakInfo struct {
+	// Key is a unique lock key.
+	Key string `json:"key"`
+	// Owner is a token of the previous lock holder.
+	Owner string `json:"owner"`
+	// Reason is an explanation why the lock was broken.
+	Reason string `json:"reason"`
+	// CorrelationId is a transaction id of the break operation.
+	CorrelationId string `json:"correlation_id"`
+	// Host is a host name of the process that broke the lock.
+	Host string `json:"host"`
+	// Pid is an id of the process that broke the lock.
+	Pid string `json:"pid"`
+	// Service is a name of the service that broke the lock.
+	Service string `json:"service"`
+	// BrokenAt is a time when the lock was broken.
+	BrokenAt time.Time `json:"broken_at"`
+}
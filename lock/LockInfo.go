@@ -0,0 +1,21 @@
+package lock
+
+/*
+LockInfo are information about a lock held in Redis.
+
+See RedisLock
+*/
+type LockInfo struct {
+	// Key is a unique lock key.
+	Key string `json:"key"`
+	// Owner is a token of the lock holder.
+	Owner string `json:"owner"`
+	// Count is a number of times the lock was acquired by the owner.
+	Count int `json:"count"`
+	// Fence is a fencing token issued when the lock was acquired.
+	Fence int64 `json:"fence"`
+	// Ttl is a remaining lock time to live in milliseconds, or -1 if the lock does not expire.
+	Ttl int64 `json:"ttl"`
+	// Metadata is additional information stored with the lock by its owner.
+	Metadata map[string]string `json:"metadata"`
+}
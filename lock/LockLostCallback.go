@@ -0,0 +1,9 @@
+package lock
+
+// LockLostCallback are function called when a held lock is lost:
+// it was broken by ForceReleaseLock or expired before it was renewed.
+// Parameters:
+//   - key       a unique lock key.
+//   - token     a token of the lost lock.
+//   - reason    an explanation why the lock was lost.
+type LockLostCallback func(key string, token string, reason string)
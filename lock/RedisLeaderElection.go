@@ -0,0 +1,397 @@
+package lock
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+	cref "github.com/pip-services3-go/pip-services3-commons-go/refer"
+	cauth "github.com/pip-services3-go/pip-services3-components-go/auth"
+	ccon "github.com/pip-services3-go/pip-services3-components-go/connect"
+)
+
+/*
+RedisLeaderElection are leader election among service replicas that is implemented based on Redis in-memory database.
+
+Every candidate of a named group campaigns for a lease stored in "leader:<group>" key.
+The candidate that holds the lease is the leader. It renews the lease in background,
+and other candidates retry to take it over when the lease expires.
+
+The leader steps down on its own before the lease can expire in Redis,
+when it failed to renew the lease in time, so two candidates never consider themselves leaders at the same time.
+
+Configuration parameters:
+
+  - connection(s):
+    - discovery_key:         (optional) a key to retrieve the connection from IDiscovery
+    - host:                  host name or IP address
+    - port:                  port number
+    - uri:                   resource URI or connection string with all parameters in it
+  - credential(s):
+    - store_key:             key to retrieve parameters from credential store
+    - username:              user name (currently is not used)
+    - password:              user password
+  - options:
+    - group:                 name of the leader election group
+    - candidate_id:          unique id of this candidate (default: generated id)
+    - lease_timeout:         leadership lease timeout in milliseconds (default: 10000)
+    - renew_interval:        interval in milliseconds to renew the lease (default: 1/3 of lease_timeout)
+    - retry_timeout:         interval in milliseconds to campaign for the leadership (default: 1000)
+    - drift_factor:          clock drift factor subtracted from the lease validity (default: 0.01)
+    - timeout:               connection timeout in milliseconds (default: 30000)
+    - db_num:                database number in Redis  (default 0)
+
+References:
+
+- *:discovery:*:*:1.0        (optional) IDiscovery services to resolve connection
+- *:credential-store:*:*:1.0 (optional) Credential stores to resolve credential
+
+Example:
+
+	election = NewRedisLeaderElection();
+	election.Configure(cconf.NewConfigParamsFromTuples(
+	  "host", "localhost",
+	  "port", 6379,
+	  "options.group", "scheduler",
+	));
+
+	election.SetOnElected(func(group string, candidateId string) {
+		// Start scheduling...
+	})
+	election.SetOnRevoked(func(group string, candidateId string) {
+		// Stop scheduling...
+	})
+
+	err = election.Open("123")
+	  ...
+
+	leaderId, err := election.GetLeaderId("123")
+*/
+type RedisLeaderElection struct {
+	connectionResolver *ccon.ConnectionResolver
+	credentialResolver *cauth.CredentialResolver
+
+	timeout       int
+	retryTimeout  int64
+	leaseTimeout  int64
+	renewInterval int64
+	driftFactor   float64
+	dbNum         int
+
+	group       string
+	candidateId string
+
+	leader  bool
+	elected LeadershipCallback
+	revoked LeadershipCallback
+	stateMx sync.Mutex
+	stop    chan struct{}
+	stopped chan struct{}
+
+	client *redis.Pool
+}
+
+// NewRedisLeaderElection method are creates a new instance of this leader election.
+func NewRedisLeaderElection() *RedisLeaderElection {
+	c := &RedisLeaderElection{
+		connectionResolver: ccon.NewEmptyConnectionResolver(),
+		credentialResolver: cauth.NewEmptyCredentialResolver(),
+		timeout:            30000,
+		retryTimeout:       1000,
+		leaseTimeout:       10000,
+		driftFactor:        0.01,
+		dbNum:              0,
+		candidateId:        cdata.IdGenerator.NextLong(),
+		client:             nil,
+	}
+	return c
+}
+
+// Configure method are configures component by passing configuration parameters.
+// Parameters:
+//  - config    configuration parameters to be set.
+func (c *RedisLeaderElection) Configure(config *cconf.ConfigParams) {
+	c.connectionResolver.Configure(config)
+	c.credentialResolver.Configure(config)
+
+	c.timeout = config.GetAsIntegerWithDefault("options.timeout", c.timeout)
+	c.retryTimeout = config.GetAsLongWithDefault("options.retry_timeout", c.retryTimeout)
+	c.leaseTimeout = config.GetAsLongWithDefault("options.lease_timeout", c.leaseTimeout)
+	c.renewInterval = config.GetAsLongWithDefault("options.renew_interval", c.renewInterval)
+	c.driftFactor = config.GetAsDoubleWithDefault("options.drift_factor", c.driftFactor)
+	c.dbNum = config.GetAsIntegerWithDefault("options.db_num", c.dbNum)
+	if c.dbNum > 15 || c.dbNum < 0 {
+		c.dbNum = 0
+	}
+	c.group = config.GetAsStringWithDefault("options.group", c.group)
+	c.candidateId = config.GetAsStringWithDefault("options.candidate_id", c.candidateId)
+}
+
+// SetReferences method are sets references to dependent components.
+// Parameters:
+//  - references 	references to locate the component dependencies.
+func (c *RedisLeaderElection) SetReferences(references cref.IReferences) {
+	c.connectionResolver.SetReferences(references)
+	c.credentialResolver.SetReferences(references)
+}
+
+// SetOnElected method are sets a callback that is called when this candidate becomes the leader.
+// The callback is called from the election goroutine and shall return quickly.
+// Parameters:
+//  - callback 	a function called when the leadership is gained.
+func (c *RedisLeaderElection) SetOnElected(callback LeadershipCallback) {
+	c.stateMx.Lock()
+	defer c.stateMx.Unlock()
+
+	c.elected = callback
+}
+
+// SetOnRevoked method are sets a callback that is called when this candidate stops being the leader:
+// the lease was taken over, it could not be renewed in time, or the component was closed.
+// The callback is called from the election goroutine and shall return quickly.
+// Parameters:
+//  - callback 	a function called when the leadership is lost.
+func (c *RedisLeaderElection) SetOnRevoked(callback LeadershipCallback) {
+	c.stateMx.Lock()
+	defer c.stateMx.Unlock()
+
+	c.revoked = callback
+}
+
+// GetGroup method are gets a name of the leader election group.
+// Returns: the group name.
+func (c *RedisLeaderElection) GetGroup() string {
+	return c.group
+}
+
+// GetCandidateId method are gets a unique id of this candidate.
+// Returns: the candidate id.
+func (c *RedisLeaderElection) GetCandidateId() string {
+	return c.candidateId
+}
+
+// IsLeader method are checks if this candidate is currently the leader.
+// Returns: true if this candidate holds a valid lease and false otherwise.
+func (c *RedisLeaderElection) IsLeader() bool {
+	c.stateMx.Lock()
+	defer c.stateMx.Unlock()
+
+	return c.leader
+}
+
+// IsOpen method are checks if the component is opened.
+// Returns true if the component has been opened and false otherwise.
+func (c *RedisLeaderElection) IsOpen() bool {
+	return c.client != nil
+}
+
+// Open method are opens the component and starts campaigning for the leadership.
+// Parameters:
+//  - correlationId 	(optional) transaction id to trace execution through call chain.
+// Returns: error or nil no errors occured.
+func (c *RedisLeaderElection) Open(correlationId string) error {
+	if c.group == "" {
+		err := cerr.NewConfigError(correlationId, "NO_GROUP", "Leader election group is not configured")
+		return err
+	}
+
+	connection, err := c.connectionResolver.Resolve(correlationId)
+	if err != nil {
+		return err
+	}
+	if connection == nil {
+		err = cerr.NewConfigError(correlationId, "NO_CONNECTION", "Connection is not configured")
+		return err
+	}
+
+	credential, err := c.credentialResolver.Lookup(correlationId)
+	if err != nil {
+		return err
+	}
+
+	var dialOpts []redis.DialOption = make([]redis.DialOption, 0)
+
+	dialOpts = append(dialOpts, redis.DialConnectTimeout(time.Duration(c.timeout)*time.Millisecond))
+	dialOpts = append(dialOpts, redis.DialDatabase(c.dbNum))
+
+	if credential != nil {
+		dialOpts = append(dialOpts, redis.DialPassword(credential.Password()))
+	}
+
+	client := newRedisPool(connection, dialOpts)
+	if err = pingRedisPool(client); err != nil {
+		client.Close()
+		return err
+	}
+
+	c.client = client
+	c.stop = make(chan struct{})
+	c.stopped = make(chan struct{})
+	go c.campaign(correlationId, c.stop, c.stopped)
+	return nil
+}
+
+// Close method are steps down from the leadership, closes component and frees used resources.
+// Parameters:
+//  - correlationId 	(optional) transaction id to trace execution through call chain.
+// Retruns: error or nil no errors occured.
+func (c *RedisLeaderElection) Close(correlationId string) error {
+	if c.client != nil {
+		close(c.stop)
+		<-c.stopped
+
+		if c.IsLeader() {
+			conn := c.client.Get()
+			redlockReleaseScript.Do(conn, c.leaderKey(), c.candidateId)
+			conn.Close()
+
+			c.revoke()
+		}
+
+		err := c.client.Close()
+		c.client = nil
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *RedisLeaderElection) checkOpened(correlationId string) (state bool, err error) {
+	if !c.IsOpen() {
+		err = cerr.NewInvalidStateError(correlationId, "NOT_OPENED", "Connection is not opened")
+		return false, err
+	}
+
+	return true, nil
+}
+
+// GetLeaderId method are gets an id of the current leader of the group.
+// Parameters:
+//  - correlationId 	(optional) transaction id to trace execution through call chain.
+// Returns: the leader id or empty string if the group has no leader, or error.
+func (c *RedisLeaderElection) GetLeaderId(correlationId string) (leaderId string, err error) {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return "", err
+	}
+
+	conn := c.client.Get()
+	defer conn.Close()
+
+	leaderId, err = redis.String(conn.Do("GET", c.leaderKey()))
+	if err == redis.ErrNil {
+		return "", nil
+	}
+	return leaderId, err
+}
+
+func (c *RedisLeaderElection) leaderKey() string {
+	return "leader:" + c.group
+}
+
+// renewLeaseScript resets the lease timeout only when it is still held by the given candidate.
+var renewLeaseScript = redis.NewScript(1, `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// campaign takes the lease when it is free and renews it while this candidate is the leader.
+// The leadership is revoked when the lease was not renewed before its validity expired.
+func (c *RedisLeaderElection) campaign(correlationId string, stop chan struct{}, stopped chan struct{}) {
+	defer close(stopped)
+
+	renewInterval := time.Duration(c.renewInterval) * time.Millisecond
+	if renewInterval <= 0 {
+		renewInterval = time.Duration(c.leaseTimeout) * time.Millisecond / 3
+	}
+	retryInterval := time.Duration(c.retryTimeout) * time.Millisecond
+
+	// The lease is considered valid a bit shorter than in Redis to compensate clock drift
+	drift := time.Duration(float64(c.leaseTimeout)*c.driftFactor)*time.Millisecond + 2*time.Millisecond
+	validity := time.Duration(c.leaseTimeout)*time.Millisecond - drift
+
+	var expiration <-chan time.Time
+	var wait time.Duration
+	for {
+		startTime := time.Now()
+		if c.IsLeader() {
+			renewed, err := c.renewLease()
+			if err == nil && !renewed {
+				expiration = nil
+				c.revoke()
+			} else if err == nil {
+				expiration = time.After(validity - time.Since(startTime))
+			}
+		} else {
+			elected, err := c.acquireLease()
+			if err == nil && elected {
+				expiration = time.After(validity - time.Since(startTime))
+				c.elect()
+			}
+		}
+
+		if c.IsLeader() {
+			wait = renewInterval
+		} else {
+			wait = retryInterval
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-expiration:
+			// The lease could not be renewed in time and may be taken over by another candidate
+			expiration = nil
+			c.revoke()
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (c *RedisLeaderElection) acquireLease() (bool, error) {
+	conn := c.client.Get()
+	defer conn.Close()
+
+	res, err := redis.String(conn.Do("SET", c.leaderKey(), c.candidateId, "NX", "PX", c.leaseTimeout))
+	if err == redis.ErrNil {
+		return false, nil
+	}
+	return res == "OK", err
+}
+
+func (c *RedisLeaderElection) renewLease() (bool, error) {
+	conn := c.client.Get()
+	defer conn.Close()
+
+	res, err := redis.Int(renewLeaseScript.Do(conn, c.leaderKey(), c.candidateId, c.leaseTimeout))
+	return res == 1, err
+}
+
+func (c *RedisLeaderElection) elect() {
+	c.stateMx.Lock()
+	c.leader = true
+	callback := c.elected
+	c.stateMx.Unlock()
+
+	if callback != nil {
+		callback(c.group, c.candidateId)
+	}
+}
+
+func (c *RedisLeaderElection) revoke() {
+	c.stateMx.Lock()
+	wasLeader := c.leader
+	c.leader = false
+	callback := c.revoked
+	c.stateMx.Unlock()
+
+	if wasLeader && callback != nil {
+		callback(c.group, c.candidateId)
+	}
+}
@@ -1,7 +1,15 @@
 package lock
 
 import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"runtime"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gomodule/redigo/redis"
@@ -11,7 +19,9 @@ import (
 	cref "github.com/pip-services3-go/pip-services3-commons-go/refer"
 	cauth "github.com/pip-services3-go/pip-services3-components-go/auth"
 	ccon "github.com/pip-services3-go/pip-services3-components-go/connect"
+	cinfo "github.com/pip-services3-go/pip-services3-components-go/info"
 	clock "github.com/pip-services3-go/pip-services3-components-go/lock"
+	clog "github.com/pip-services3-go/pip-services3-components-go/log"
 )
 
 /*
@@ -29,7 +39,14 @@ Configuration parameters:
     - username:              user name (currently is not used)
     - password:              user password
   - options:
-    - retrytimeout:         timeout in milliseconds to retry lock acquisition. (Default: 100)
+    - retry_timeout:         timeout in milliseconds to retry lock acquisition when it has no timeout. (Default: 100)
+    - auto_renew:            renew held locks in background until they are released (default: false)
+    - renew_fraction:        fraction of the lock TTL after which the lock is renewed (default: 0.33)
+    - fair:                  grant locks to waiters in order of their arrival (default: false)
+    - release_on_close:      release the locks held by the component when it is closed (default: true)
+    - close_timeout:         timeout in milliseconds to release the held locks on close, 0 to wait until done (default: 5000)
+    - debug:                 capture places that acquire locks and report locks held for too long (default: false)
+    - debug_threshold:       time in milliseconds after which a held lock is reported in debug mode (default: 60000)
     - retries:               number of retries (default: 3)
     - db_num:                database number in Redis  (default 0)
 
@@ -37,6 +54,8 @@ References:
 
 - *:discovery:*:*:1.0        (optional) IDiscovery services to resolve connection
 - *:credential-store:*:*:1.0 (optional) Credential stores to resolve credential
+- *:context-info:*:*:1.0     (optional) ContextInfo to detect the service name stored with the locks
+- *:logger:*:*:1.0           (optional) ILogger components to report ambiguous ReleaseLock calls and locks held for too long in debug mode
 
 Example:
 
@@ -55,18 +74,122 @@ Example:
     }
     err = lock.ReleaseLock("123", "key1")
     // Continue...
+
+Locks acquired through TryAcquireLock and AcquireLock are owned by the component instance,
+and ReleaseLock is deprecated since any caller can release them by their keys.
+When the component is shared between several goroutines use the token methods instead,
+so every acquisition gets its own token and can only be released by its holder:
+
+    token, err := lock.TryAcquireLockWithToken("123", "key1", 3000)
+    if token != "" {
+    	defer lock.ReleaseLockWithToken("123", "key1", token)
+    	// Processing...
+    }
+
+Long running jobs can extend their locks explicitly with ExtendLock,
+or enable "options.auto_renew" to let the component renew the held locks in background.
+
+Nested calls that lock the same key can share an owner token to acquire the lock reentrantly.
+The lock is freed when it was released as many times as it was acquired:
+
+    token, err := lock.TryAcquireLockWithToken("123", "key1", 3000)
+    ...
+    result, err := lock.TryAcquireLockForOwner("123", "key1", token, 3000) // result is true
+    ...
+    err = lock.ReleaseLockWithToken("123", "key1", token) // still held
+    err = lock.ReleaseLockWithToken("123", "key1", token) // released
+
+Locks are stored in Redis as hashes, while the previous versions stored them as plain strings.
+Old and new versions cannot share lock keys: they fail on each other's locks with WRONGTYPE errors.
+Stop all instances of the old version before starting the new one, or use a different key prefix
+for the new version during rolling upgrades.
+
+In fair mode ("options.fair") waiters line up in a Redis queue and the lock is granted
+in order of their arrival. Waiters block on their own signal lists
+and are woken up by the previous holder when it releases the lock.
+A waiter that crashes is dropped from the queue after a few seconds.
+
+Otherwise released locks are announced on "<key>:released" channel,
+so AcquireLock retries as soon as the lock is released instead of polling Redis.
+
+Every lock keeps metadata about its owner next to the owner token: "host", "pid",
+"service", "acquired_at" and "correlation_id". Held locks can be inspected
+with IsLocked, GetLockInfo and ListLocks:
+
+    locks, err := lock.ListLocks("123", "jobs:*")
+    for _, info := range locks {
+    	fmt.Println(info.Key, info.Metadata["service"], info.Metadata["host"], info.Ttl)
+    }
+
+Locks left by hung processes can be broken with ForceReleaseLock. Every break is recorded
+in "<key>:breaks" list and announced on "lock:lost" channel, so the previous owner
+stops renewing the lock and learns about the loss through the callback:
+
+    lock.SetLockLostCallback(func(key string, token string, reason string) {
+    	// Abort processing...
+    })
+
+Every acquisition also gets a fencing token from "<key>:fence" counter that is incremented
+atomically in Redis. Pass it along with writes to downstream storages,
+so they can reject writes from a holder whose lock has already expired:
+
+    token, fence, err := lock.AcquireLockWithFencing("123", "key1", 3000, 1000)
+    if err == nil {
+    	defer lock.ReleaseLockWithToken("123", "key1", token)
+    	err = storage.Write("123", data, fence) // rejects fences lower than the last one seen
+    }
+
+Several keys can be locked at once with TryAcquireLocks and AcquireLocks.
+A Lua script acquires either all the locks or none of them, so operations that lock
+the same keys in a different order cannot deadlock or keep a part of the locks:
+
+    token, err := lock.AcquireLocks("123", []string{"account:1", "account:2"}, 3000, 1000)
+    if err == nil {
+    	defer lock.ReleaseLocks("123", []string{"account:1", "account:2"}, token)
+    	// Transferring...
+    }
+
+The component keeps track of the locks it holds and releases them on Close,
+so they do not stay in Redis until their timeouts expire. Held locks can be listed
+with GetHeldLocks. In debug mode ("options.debug") the component captures the place
+in the code that acquired every lock, and logs warnings about locks held longer
+than "options.debug_threshold" and locks that were never released before Close.
 */
 type RedisLock struct {
 	*clock.Lock
 	connectionResolver *ccon.ConnectionResolver
 	credentialResolver *cauth.CredentialResolver
 
-	lockId  string
-	timeout int
+	timeout      int
+	retryTimeout int64
 	//retries int
 	dbNum int
 
-	client redis.Conn
+	autoRenew     bool
+	renewFraction float64
+	fair          bool
+
+	releaseOnClose bool
+	closeTimeout   int64
+	debug          bool
+	debugThreshold int64
+
+	host    string
+	pid     string
+	service string
+
+	tokens    map[string]string
+	held      map[string]*HeldLockInfo
+	watchdogs map[string]chan struct{}
+	closing   bool
+	tokensMx  sync.Mutex
+
+	lostCallback LockLostCallback
+	listenerStop chan struct{}
+	workers      sync.WaitGroup
+
+	logger *clog.CompositeLogger
+	client *redis.Pool
 }
 
 // NewRedisLock method are creates a new instance of this lock.
@@ -74,12 +197,22 @@ func NewRedisLock() *RedisLock {
 	c := &RedisLock{
 		connectionResolver: ccon.NewEmptyConnectionResolver(),
 		credentialResolver: cauth.NewEmptyCredentialResolver(),
-		lockId:             cdata.IdGenerator.NextLong(),
 		timeout:            30000,
+		retryTimeout:       100,
 		//retries : 3,
-		dbNum:  0,
-		client: nil,
+		dbNum:          0,
+		renewFraction:  0.33,
+		releaseOnClose: true,
+		closeTimeout:   5000,
+		debugThreshold: 60000,
+		tokens:         map[string]string{},
+		held:           map[string]*HeldLockInfo{},
+		watchdogs:      map[string]chan struct{}{},
+		pid:            strconv.Itoa(os.Getpid()),
+		logger:         clog.NewCompositeLogger(),
+		client:         nil,
 	}
+	c.host, _ = os.Hostname()
 	c.Lock = clock.InheritLock(c)
 	return c
 }
@@ -90,13 +223,26 @@ func NewRedisLock() *RedisLock {
 func (c *RedisLock) Configure(config *cconf.ConfigParams) {
 	c.connectionResolver.Configure(config)
 	c.credentialResolver.Configure(config)
+	c.Lock.Configure(config)
+	c.logger.Configure(config)
 
 	c.timeout = config.GetAsIntegerWithDefault("options.timeout", c.timeout)
+	c.retryTimeout = config.GetAsLongWithDefault("options.retry_timeout", c.retryTimeout)
 	//c.retries = config.GetAsIntegerWithDefault("options.retries", c.retries)
 	c.dbNum = config.GetAsIntegerWithDefault("options.db_num", c.dbNum)
 	if c.dbNum > 15 || c.dbNum < 0 {
 		c.dbNum = 0
 	}
+	c.autoRenew = config.GetAsBooleanWithDefault("options.auto_renew", c.autoRenew)
+	c.renewFraction = config.GetAsDoubleWithDefault("options.renew_fraction", c.renewFraction)
+	if c.renewFraction <= 0 || c.renewFraction >= 1 {
+		c.renewFraction = 0.33
+	}
+	c.fair = config.GetAsBooleanWithDefault("options.fair", c.fair)
+	c.releaseOnClose = config.GetAsBooleanWithDefault("options.release_on_close", c.releaseOnClose)
+	c.closeTimeout = config.GetAsLongWithDefault("options.close_timeout", c.closeTimeout)
+	c.debug = config.GetAsBooleanWithDefault("options.debug", c.debug)
+	c.debugThreshold = config.GetAsLongWithDefault("options.debug_threshold", c.debugThreshold)
 }
 
 // SetReferences method are sets references to dependent components.
@@ -105,6 +251,24 @@ func (c *RedisLock) Configure(config *cconf.ConfigParams) {
 func (c *RedisLock) SetReferences(references cref.IReferences) {
 	c.connectionResolver.SetReferences(references)
 	c.credentialResolver.SetReferences(references)
+	c.logger.SetReferences(references)
+
+	ref := references.GetOneOptional(
+		cref.NewDescriptor("pip-services", "context-info", "*", "*", "1.0"))
+	if contextInfo, ok := ref.(*cinfo.ContextInfo); ok && contextInfo != nil {
+		c.service = contextInfo.Name
+	}
+}
+
+// SetLockLostCallback method are sets a callback that is called
+// when a lock held by this component is broken by someone else or expires before it was renewed.
+// Parameters:
+//   - callback 	a function called when a lock is lost.
+func (c *RedisLock) SetLockLostCallback(callback LockLostCallback) {
+	c.tokensMx.Lock()
+	defer c.tokensMx.Unlock()
+
+	c.lostCallback = callback
 }
 
 // IsOpen method are checks if the component is opened.
@@ -133,33 +297,35 @@ func (c *RedisLock) Open(correlationId string) error {
 		return err
 	}
 
-	var url, host, port, password string
 	var dialOpts []redis.DialOption = make([]redis.DialOption, 0)
 
 	dialOpts = append(dialOpts, redis.DialConnectTimeout(time.Duration(c.timeout)*time.Millisecond))
 	dialOpts = append(dialOpts, redis.DialDatabase(c.dbNum))
 
 	if credential != nil {
-		password = credential.Password()
-		dialOpts = append(dialOpts, redis.DialPassword(password))
+		dialOpts = append(dialOpts, redis.DialPassword(credential.Password()))
 	}
 
-	if connection.Uri() != "" {
-		url = connection.Uri()
-		c.client, err = redis.DialURL(url, dialOpts...)
-	} else {
-		host = connection.Host()
-		if host == "" {
-			host = "localhost"
-		}
-		port = strconv.FormatInt(int64(connection.Port()), 10)
-		if port == "0" {
-			port = "6379"
-		}
-		url = host + ":" + port
-		c.client, err = redis.Dial("tcp", url, dialOpts...)
+	// Check the connection before the component is considered opened
+	client := newRedisPool(connection, dialOpts)
+	if err = pingRedisPool(client); err != nil {
+		client.Close()
+		return err
 	}
-	return err
+
+	c.tokensMx.Lock()
+	c.closing = false
+	c.tokensMx.Unlock()
+
+	c.client = client
+	c.listenerStop = make(chan struct{})
+	c.workers.Add(1)
+	go c.listenLostLocks(client, c.listenerStop)
+	if c.debug {
+		c.workers.Add(1)
+		go c.reportHeldLocks(c.listenerStop)
+	}
+	return nil
 }
 
 // Close method are closes component and frees used resources.
@@ -168,6 +334,28 @@ func (c *RedisLock) Open(correlationId string) error {
 // Retruns: error or nil no errors occured.
 func (c *RedisLock) Close(correlationId string) error {
 	if c.client != nil {
+		close(c.listenerStop)
+
+		// New acquisitions are refused from now on, so no watchdogs are started
+		// after the running ones are stopped
+		c.tokensMx.Lock()
+		c.closing = true
+		for _, stop := range c.watchdogs {
+			close(stop)
+		}
+		held := c.held
+		c.watchdogs = map[string]chan struct{}{}
+		c.tokens = map[string]string{}
+		c.held = map[string]*HeldLockInfo{}
+		c.tokensMx.Unlock()
+
+		// Background goroutines use the client, so they must finish before it is closed
+		c.workers.Wait()
+
+		if c.releaseOnClose {
+			c.releaseHeldLocks(correlationId, c.client, held)
+		}
+
 		err := c.client.Close()
 		c.client = nil
 		if err != nil {
@@ -177,6 +365,46 @@ func (c *RedisLock) Close(correlationId string) error {
 	return nil
 }
 
+// releaseHeldLocks removes the locks held by the component, so they are not left in Redis
+// until their timeouts expire. It waits for the release not longer than the close timeout.
+func (c *RedisLock) releaseHeldLocks(correlationId string, client *redis.Pool, held map[string]*HeldLockInfo) {
+	if len(held) == 0 {
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		conn := client.Get()
+		defer conn.Close()
+
+		for _, info := range held {
+			if c.debug {
+				c.logger.Warn(info.CorrelationId, "Lock %s was never released, acquired at %s", info.Key, info.Site)
+			}
+
+			res, err := redis.Int(dropScript.Do(conn, info.Key, info.Token))
+			if err != nil {
+				c.logger.Error(correlationId, err, "Failed to release lock %s on close", info.Key)
+			} else if res == 1 {
+				c.notifyWaiters(conn, info.Key, info.Token)
+			}
+		}
+	}()
+
+	if c.closeTimeout <= 0 {
+		<-done
+		return
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Duration(c.closeTimeout) * time.Millisecond):
+		c.logger.Warn(correlationId, "Releasing held locks on close failed on timeout")
+	}
+}
+
 func (c *RedisLock) checkOpened(correlationId string) (state bool, err error) {
 	if !c.IsOpen() {
 		err = cerr.NewInvalidStateError(correlationId, "NOT_OPENED", "Connection is not opened")
@@ -186,27 +414,496 @@ func (c *RedisLock) checkOpened(correlationId string) (state bool, err error) {
 	return true, nil
 }
 
+// checkAcquirable checks that the component is opened and does not close,
+// since locks acquired while it closes could not be tracked and released.
+func (c *RedisLock) checkAcquirable(correlationId string) (state bool, err error) {
+	if state, err = c.checkOpened(correlationId); !state {
+		return false, err
+	}
+
+	c.tokensMx.Lock()
+	closing := c.closing
+	c.tokensMx.Unlock()
+	if closing {
+		return false, newClosingError(correlationId)
+	}
+
+	return true, nil
+}
+
+func newClosingError(correlationId string) error {
+	return cerr.NewInvalidStateError(correlationId, "CLOSING", "Lock is closing")
+}
+
+// Locks are stored as hashes with the owner token, the number of holds, the fencing token
+// and the owner metadata, so the same owner can acquire the lock several times.
+// The metadata is passed to the scripts as trailing field/value pairs.
+// Fencing tokens are generated by "<key>:fence" counter that never expires,
+// so they keep growing across acquisitions.
+
+// acquireScript acquires a lock or increments its hold count when it is already held by the owner.
+// It returns the hold count and the fencing token, or zeros when the lock is held by someone else.
+var acquireScript = redis.NewScript(2, `
+local owner = redis.call("HGET", KEYS[1], "owner")
+if not owner then
+	local fence = redis.call("INCR", KEYS[2])
+	redis.call("HMSET", KEYS[1], "owner", ARGV[1], "count", 1, "fence", fence, unpack(ARGV, 3))
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	return {1, fence}
+end
+if owner == ARGV[1] then
+	local count = redis.call("HINCRBY", KEYS[1], "count", 1)
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	return {count, tonumber(redis.call("HGET", KEYS[1], "fence"))}
+end
+return {0, 0}
+`)
+
+// releaseScript decrements a lock hold count when it is held by the given token
+// and removes the lock when the count drops to zero.
+// It returns 1 when the lock was removed, 0 when it is still held and -1 when it is not owned.
+var releaseScript = redis.NewScript(1, `
+if redis.call("HGET", KEYS[1], "owner") ~= ARGV[1] then
+	return -1
+end
+if redis.call("HINCRBY", KEYS[1], "count", -1) > 0 then
+	return 0
+end
+redis.call("DEL", KEYS[1])
+return 1
+`)
+
+// dropScript removes a lock regardless of its hold count when it is still held by the given token.
+var dropScript = redis.NewScript(1, `
+if redis.call("HGET", KEYS[1], "owner") == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// extendScript resets a lock timeout only when it is still held by the given token.
+var extendScript = redis.NewScript(1, `
+if redis.call("HGET", KEYS[1], "owner") == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// Fair locks keep their waiters in additional keys:
+//   - <key>:queue         a sorted set of waiter tokens scored by their arrival tickets
+//   - <key>:alive         a sorted set of waiter tokens scored by their expiration time
+//   - <key>:ticket        a counter of arrival tickets
+//   - <key>:signal:<token> a list the waiter blocks on until the lock is released
+//   - <key>:fence         a counter of fencing tokens
+
+// fairWaitTimeout is a time in milliseconds after which a silent waiter is dropped from the queue.
+const fairWaitTimeout int64 = 3000
+
+// fairBlockTimeout is a time in seconds a waiter blocks on its signal list before it checks the lock again.
+const fairBlockTimeout int64 = 1
+
+// fairAcquireScript acquires a lock when it is free and the owner is the first in the queue.
+// Waiters are registered in the queue when the wait timeout is given, and dead waiters are dropped.
+// It returns the hold count and the fencing token, or zeros when the lock is not granted.
+var fairAcquireScript = redis.NewScript(5, `
+redis.replicate_commands()
+local owner = redis.call("HGET", KEYS[1], "owner")
+if owner == ARGV[1] then
+	local count = redis.call("HINCRBY", KEYS[1], "count", 1)
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	return {count, tonumber(redis.call("HGET", KEYS[1], "fence"))}
+end
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local wait = tonumber(ARGV[3])
+if wait > 0 then
+	if not redis.call("ZSCORE", KEYS[2], ARGV[1]) then
+		redis.call("ZADD", KEYS[2], redis.call("INCR", KEYS[4]), ARGV[1])
+	end
+	redis.call("ZADD", KEYS[3], now + wait, ARGV[1])
+	redis.call("PEXPIRE", KEYS[2], wait)
+	redis.call("PEXPIRE", KEYS[3], wait)
+	redis.call("PEXPIRE", KEYS[4], wait)
+end
+local dead = redis.call("ZRANGEBYSCORE", KEYS[3], "-inf", now)
+for _, token in ipairs(dead) do
+	redis.call("ZREM", KEYS[2], token)
+end
+redis.call("ZREMRANGEBYSCORE", KEYS[3], "-inf", now)
+if owner then
+	return {0, 0}
+end
+local head = redis.call("ZRANGE", KEYS[2], 0, 0)
+if head[1] and head[1] ~= ARGV[1] then
+	return {0, 0}
+end
+local fence = redis.call("INCR", KEYS[5])
+redis.call("HMSET", KEYS[1], "owner", ARGV[1], "count", 1, "fence", fence, unpack(ARGV, 4))
+redis.call("PEXPIRE", KEYS[1], ARGV[2])
+redis.call("ZREM", KEYS[2], ARGV[1])
+redis.call("ZREM", KEYS[3], ARGV[1])
+return {1, fence}
+`)
+
+// composeMetadata composes owner metadata stored with acquired locks as field/value pairs.
+func (c *RedisLock) composeMetadata(correlationId string) []interface{} {
+	return []interface{}{
+		"host", c.host,
+		"pid", c.pid,
+		"service", c.service,
+		"acquired_at", time.Now().UTC().Format(time.RFC3339Nano),
+		"correlation_id", correlationId,
+	}
+}
+
+// AcquireLock method are acquires a lock by its key.
+// When the lock is held by someone else it retries until the timeout expires.
+// The acquired lock is owned by the component and can be released by ReleaseLock.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - key               a unique lock key to acquire.
+//  - ttl               a lock timeout (time to live) in milliseconds.
+//  - timeout           a lock acquisition timeout in milliseconds.
+// Returns: error or nil for success.
+func (c *RedisLock) AcquireLock(correlationId string, key string, ttl int64, timeout int64) error {
+	token, err := c.AcquireLockWithToken(correlationId, key, ttl, timeout)
+	if err != nil {
+		return err
+	}
+
+	c.setLegacyToken(correlationId, key, token)
+	return nil
+}
+
 // TryAcquireLock method are makes a single attempt to acquire a lock by its key.
 // It returns immediately a positive or negative result.
+// The acquired lock is owned by the component and can be released by ReleaseLock.
 // Parameters:
 //  - correlationId     (optional) transaction id to trace execution through call chain.
 //  - key               a unique lock key to acquire.
 //  - ttl               a lock timeout (time to live) in milliseconds.
 // Returns: a lock result or error.
 func (c *RedisLock) TryAcquireLock(correlationId string, key string, ttl int64) (result bool, err error) {
-	state, err := c.checkOpened(correlationId)
-	if !state {
+	token, err := c.TryAcquireLockWithToken(correlationId, key, ttl)
+	if token == "" || err != nil {
 		return false, err
 	}
 
-	res, err := redis.String(c.client.Do("SET", key, c.lockId, "NX", "PX", ttl))
-	if err != nil && err == redis.ErrNil {
-		return false, nil
+	c.setLegacyToken(correlationId, key, token)
+	return true, nil
+}
+
+// setLegacyToken remembers the token of the lock acquired by TryAcquireLock or AcquireLock,
+// so it can be released by ReleaseLock. A token of the previous acquisition that was never released
+// makes the key ambiguous, since the previous holder can release the new lock.
+func (c *RedisLock) setLegacyToken(correlationId string, key string, token string) {
+	c.tokensMx.Lock()
+	_, ambiguous := c.tokens[key]
+	c.tokens[key] = token
+	c.tokensMx.Unlock()
+
+	if ambiguous {
+		c.logger.Warn(correlationId, "Lock %s was acquired by key again before it was released. "+
+			"ReleaseLock releases the last acquisition, use token methods to release locks by their holders", key)
+	}
+}
+
+// TryAcquireLockWithToken method are makes a single attempt to acquire a lock by its key.
+// Every successful acquisition gets its own unique token that must be used to release the lock.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - key               a unique lock key to acquire.
+//  - ttl               a lock timeout (time to live) in milliseconds.
+// Returns: a lock token or empty string if the lock is held by someone else, or error.
+func (c *RedisLock) TryAcquireLockWithToken(correlationId string, key string, ttl int64) (token string, err error) {
+	token, _, err = c.TryAcquireLockWithFencing(correlationId, key, ttl)
+	return token, err
+}
+
+// TryAcquireLockWithFencing method are makes a single attempt to acquire a lock by its key
+// and returns its token together with a fencing token.
+// Fencing tokens strictly increase with every acquisition of the key, so downstream storages
+// can reject writes from a holder whose lock has expired and was taken over.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - key               a unique lock key to acquire.
+//  - ttl               a lock timeout (time to live) in milliseconds.
+// Returns: a lock token and a fencing token, or empty token and zero if the lock is held by someone else, or error.
+func (c *RedisLock) TryAcquireLockWithFencing(correlationId string, key string, ttl int64) (token string, fence int64, err error) {
+	token = cdata.IdGenerator.NextLong()
+	fence, err = c.tryAcquireLock(correlationId, key, token, ttl)
+	if fence == 0 || err != nil {
+		return "", 0, err
+	}
+	return token, fence, nil
+}
+
+// TryAcquireLockForOwner method are makes a single attempt to acquire a reentrant lock by its key.
+// When the lock is already held by the same owner the call succeeds and increments the lock hold count.
+// The lock is freed only when ReleaseLockWithToken was called for every successful acquisition.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - key               a unique lock key to acquire.
+//  - owner             a unique owner token, for instance a token returned by TryAcquireLockWithToken.
+//  - ttl               a lock timeout (time to live) in milliseconds.
+// Returns: a lock result or error.
+func (c *RedisLock) TryAcquireLockForOwner(correlationId string, key string, owner string, ttl int64) (result bool, err error) {
+	fence, err := c.tryAcquireLock(correlationId, key, owner, ttl)
+	return fence > 0, err
+}
+
+// tryAcquireLock makes a single attempt to acquire a reentrant lock.
+// It returns the fencing token of the lock or zero when the lock is held by someone else.
+func (c *RedisLock) tryAcquireLock(correlationId string, key string, owner string, ttl int64) (fence int64, err error) {
+	state, err := c.checkAcquirable(correlationId)
+	if !state {
+		return 0, err
+	}
+
+	conn := c.client.Get()
+	defer conn.Close()
+
+	var res []int64
+	if c.fair {
+		// Single attempts do not take a place in the queue and cannot overtake waiters
+		args := []interface{}{key, key + ":queue", key + ":alive", key + ":ticket", key + ":fence", owner, ttl, 0}
+		res, err = redis.Int64s(fairAcquireScript.Do(conn, append(args, c.composeMetadata(correlationId)...)...))
+	} else {
+		args := []interface{}{key, key + ":fence", owner, ttl}
+		res, err = redis.Int64s(acquireScript.Do(conn, append(args, c.composeMetadata(correlationId)...)...))
+	}
+	if err != nil || res[0] == 0 {
+		return 0, err
+	}
+
+	if res[0] == 1 {
+		if err = c.onAcquired(correlationId, key, owner, ttl); err != nil {
+			c.giveBack(conn, key, owner)
+			return 0, err
+		}
+	}
+	return res[1], nil
+}
+
+// AcquireLockForOwner method are acquires a reentrant lock by its key.
+// When the lock is held by another owner it retries until the timeout expires.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - key               a unique lock key to acquire.
+//  - owner             a unique owner token, for instance a token returned by TryAcquireLockWithToken.
+//  - ttl               a lock timeout (time to live) in milliseconds.
+//  - timeout           a lock acquisition timeout in milliseconds.
+// Returns: error or nil for success.
+func (c *RedisLock) AcquireLockForOwner(correlationId string, key string, owner string, ttl int64, timeout int64) error {
+	_, err := c.acquireLockForOwner(correlationId, key, owner, ttl, timeout)
+	return err
+}
+
+// acquireLockForOwner acquires a reentrant lock and returns its fencing token,
+// or fails with conflict error when the acquisition timeout expires.
+func (c *RedisLock) acquireLockForOwner(correlationId string, key string, owner string, ttl int64, timeout int64) (fence int64, err error) {
+	if c.fair {
+		fence, err = c.acquireFairLock(correlationId, key, owner, ttl, timeout)
+	} else {
+		fence, err = c.acquireLock(correlationId, key, owner, ttl, timeout)
+	}
+	if fence > 0 || err != nil {
+		return fence, err
+	}
+
+	err = cerr.NewConflictError(
+		correlationId,
+		"LOCK_TIMEOUT",
+		"Acquiring lock "+key+" failed on timeout",
+	).WithDetails("key", key)
+	return 0, err
+}
+
+// acquireLock retries to acquire the lock every time it is released by the previous holder
+// until the acquisition timeout expires. Releases are received through the lock channel,
+// and when the holder crashes the lock is retried after its expiration.
+func (c *RedisLock) acquireLock(correlationId string, key string, owner string, ttl int64, timeout int64) (fence int64, err error) {
+	state, err := c.checkAcquirable(correlationId)
+	if !state {
+		return 0, err
+	}
+
+	// Subscribe before the first attempt, so releases in between are not missed
+	released, unsubscribe, err := c.subscribeReleases(key)
+	if err != nil {
+		return 0, err
+	}
+	defer unsubscribe()
+
+	expireTime := time.Now().Add(time.Duration(timeout) * time.Millisecond)
+
+	for time.Now().Before(expireTime) {
+		fence, err = c.tryAcquireLock(correlationId, key, owner, ttl)
+		if fence > 0 || err != nil {
+			return fence, err
+		}
+
+		// Wait for the release, but not longer than the lock is going to live
+		wait := time.Until(expireTime)
+		if pttl, err := c.getLockTimeout(key); err == nil && pttl > 0 && pttl < wait {
+			wait = pttl
+		} else if err == nil && pttl <= 0 {
+			wait = time.Duration(c.retryTimeout) * time.Millisecond
+		}
+
+		select {
+		case <-released:
+		case <-time.After(wait):
+		}
+	}
+	return 0, nil
+}
+
+// subscribeReleases subscribes to release notifications of the locks.
+// It returns a channel signaled on every release and a function to cancel the subscription.
+func (c *RedisLock) subscribeReleases(keys ...string) (released <-chan struct{}, unsubscribe func(), err error) {
+	channels := make([]interface{}, len(keys))
+	for i, key := range keys {
+		channels[i] = key + ":released"
+	}
+
+	psc := redis.PubSubConn{Conn: c.client.Get()}
+	if err = psc.Subscribe(channels...); err != nil {
+		psc.Close()
+		return nil, nil, err
+	}
+
+	// The connection is closed by the reader when the subscription ends,
+	// since closing it under a blocked Receive is not safe
+	notifications := make(chan struct{}, 1)
+	go func() {
+		defer psc.Close()
+		for {
+			switch v := psc.Receive().(type) {
+			case redis.Message:
+				select {
+				case notifications <- struct{}{}:
+				default:
+				}
+			case redis.Subscription:
+				if v.Count == 0 {
+					return
+				}
+			case error:
+				return
+			}
+		}
+	}()
+
+	unsubscribe = func() {
+		psc.Unsubscribe()
 	}
-	return res == "OK", err
+	return notifications, unsubscribe, nil
+}
+
+// getLockTimeout gets the remaining time to live of the lock.
+func (c *RedisLock) getLockTimeout(key string) (time.Duration, error) {
+	conn := c.client.Get()
+	defer conn.Close()
+
+	pttl, err := redis.Int64(conn.Do("PTTL", key))
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(pttl) * time.Millisecond, nil
+}
+
+// acquireFairLock puts the owner into the lock queue and waits for a signal from the previous holder
+// until the lock is granted or the acquisition timeout expires.
+func (c *RedisLock) acquireFairLock(correlationId string, key string, owner string, ttl int64, timeout int64) (fence int64, err error) {
+	state, err := c.checkAcquirable(correlationId)
+	if !state {
+		return 0, err
+	}
+
+	conn := c.client.Get()
+	defer conn.Close()
+
+	signal := key + ":signal:" + owner
+	expireTime := time.Now().Add(time.Duration(timeout) * time.Millisecond)
+
+	for time.Now().Before(expireTime) {
+		args := []interface{}{key, key + ":queue", key + ":alive", key + ":ticket", key + ":fence", owner, ttl, fairWaitTimeout}
+		args = append(args, c.composeMetadata(correlationId)...)
+
+		var res []int64
+		res, err = redis.Int64s(fairAcquireScript.Do(conn, args...))
+		if err != nil {
+			break
+		}
+		if res[0] > 0 {
+			conn.Do("DEL", signal)
+			if res[0] == 1 {
+				if err = c.onAcquired(correlationId, key, owner, ttl); err != nil {
+					c.giveBack(conn, key, owner)
+					return 0, err
+				}
+			}
+			return res[1], nil
+		}
+
+		// Wait until the previous holder hands the lock over, or check again after a while
+		// in case it crashed or its lock expired
+		_, err = conn.Do("BLPOP", signal, fairBlockTimeout)
+		if err != nil && err != redis.ErrNil {
+			break
+		}
+		err = nil
+	}
+
+	// Leave the queue, so the next waiters are not blocked
+	conn.Send("MULTI")
+	conn.Send("ZREM", key+":queue", owner)
+	conn.Send("ZREM", key+":alive", owner)
+	conn.Send("DEL", signal)
+	conn.Do("EXEC")
+
+	return 0, err
+}
+
+// AcquireLockWithToken method are acquires a lock by its key and returns its token.
+// When the lock is held by someone else it retries until the timeout expires.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - key               a unique lock key to acquire.
+//  - ttl               a lock timeout (time to live) in milliseconds.
+//  - timeout           a lock acquisition timeout in milliseconds.
+// Returns: a lock token or error.
+func (c *RedisLock) AcquireLockWithToken(correlationId string, key string, ttl int64, timeout int64) (token string, err error) {
+	token, _, err = c.AcquireLockWithFencing(correlationId, key, ttl, timeout)
+	return token, err
+}
+
+// AcquireLockWithFencing method are acquires a lock by its key and returns its token
+// together with a fencing token that strictly increases with every acquisition of the key.
+// When the lock is held by someone else it retries until the timeout expires.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - key               a unique lock key to acquire.
+//  - ttl               a lock timeout (time to live) in milliseconds.
+//  - timeout           a lock acquisition timeout in milliseconds.
+// Returns: a lock token and a fencing token, or error.
+func (c *RedisLock) AcquireLockWithFencing(correlationId string, key string, ttl int64, timeout int64) (token string, fence int64, err error) {
+	token = cdata.IdGenerator.NextLong()
+	fence, err = c.acquireLockForOwner(correlationId, key, token, ttl, timeout)
+	if err != nil {
+		return "", 0, err
+	}
+	return token, fence, nil
 }
 
 // ReleaseLock method are releases prevously acquired lock by its key.
+// Only locks acquired by TryAcquireLock or AcquireLock of this component are released,
+// locks acquired with tokens can only be released by ReleaseLockWithToken.
+// Any caller that shares the component can release the lock by its key,
+// so goroutines that share the component shall use the token methods instead.
+//
+// Deprecated: use TryAcquireLockWithToken or AcquireLockWithToken and ReleaseLockWithToken.
 //  - correlationId     (optional) transaction id to trace execution through call chain.
 //  - key               a unique lock key to release.
 // Returns: error or nil for success.
@@ -216,25 +913,809 @@ func (c *RedisLock) ReleaseLock(correlationId string, key string) error {
 		return err
 	}
 
-	// Start transaction on key
-	_, err = c.client.Do("WATCH", key)
+	c.tokensMx.Lock()
+	token, ok := c.tokens[key]
+	delete(c.tokens, key)
+	c.tokensMx.Unlock()
+
+	if !ok {
+		return nil
+	}
+	return c.ReleaseLockWithToken(correlationId, key, token)
+}
+
+// ReleaseLockWithToken method are releases prevously acquired lock by its key and token.
+// The lock hold count is decremented only when it is still held by the given token,
+// and the lock is removed when the count drops to zero.
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - key               a unique lock key to release.
+//  - token             a lock token returned on acquisition.
+// Returns: error or nil for success.
+func (c *RedisLock) ReleaseLockWithToken(correlationId string, key string, token string) error {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return err
+	}
+
+	conn := c.client.Get()
+	defer conn.Close()
+
+	res, err := redis.Int(releaseScript.Do(conn, key, token))
 	if err != nil {
 		return err
 	}
 
-	// Read and check if lock is the same
-	keyId, err := redis.String(c.client.Do("GET", key))
-	if err != nil && err != redis.ErrNil {
-		c.client.Do("UNWATCH")
+	if res != 0 {
+		c.onReleased(key, token)
+	}
+	if res == 1 {
+		c.notifyWaiters(conn, key, token)
+	}
+	return nil
+}
+
+// notifyWaiters wakes up the waiters of the released lock, so they do not wait for the retry timeout.
+func (c *RedisLock) notifyWaiters(conn redis.Conn, key string, token string) {
+	if c.fair {
+		c.signalNextWaiter(conn, key)
+	} else {
+		conn.Do("PUBLISH", key+":released", token)
+	}
+}
+
+// signalNextWaiter wakes up the first waiter in the lock queue.
+func (c *RedisLock) signalNextWaiter(conn redis.Conn, key string) {
+	head, err := redis.Strings(conn.Do("ZRANGE", key+":queue", 0, 0))
+	if err != nil || len(head) == 0 {
+		return
+	}
+
+	signal := key + ":signal:" + head[0]
+	conn.Send("MULTI")
+	conn.Send("RPUSH", signal, 1)
+	conn.Send("PEXPIRE", signal, fairWaitTimeout)
+	conn.Do("EXEC")
+}
+
+// Group locks are acquired and released by a single script call, so the caller
+// never holds a part of the group. The acquisition script keys are split into four parts:
+// lock keys, their fencing counters, and the queues and alive sets of the fair mode waiters.
+
+// acquireLocksScript acquires all the locks, or none of them when any lock is held by someone else
+// or has live waiters in its fair queue. Dead waiters are dropped from the queues.
+// It returns the hold counts of the locks, or 0 followed by the index of the lock that is not granted.
+var acquireLocksScript = redis.NewScript(-1, `
+redis.replicate_commands()
+local n = #KEYS / 4
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+for i = 1, n do
+	local owner = redis.call("HGET", KEYS[i], "owner")
+	if owner and owner ~= ARGV[1] then
+		return {0, i}
+	end
+	local dead = redis.call("ZRANGEBYSCORE", KEYS[3 * n + i], "-inf", now)
+	for _, token in ipairs(dead) do
+		redis.call("ZREM", KEYS[2 * n + i], token)
+	end
+	redis.call("ZREMRANGEBYSCORE", KEYS[3 * n + i], "-inf", now)
+	if not owner and redis.call("ZCARD", KEYS[2 * n + i]) > 0 then
+		return {0, i}
+	end
+end
+local counts = {}
+for i = 1, n do
+	if redis.call("HEXISTS", KEYS[i], "owner") == 1 then
+		counts[i] = redis.call("HINCRBY", KEYS[i], "count", 1)
+	else
+		local fence = redis.call("INCR", KEYS[n + i])
+		redis.call("HMSET", KEYS[i], "owner", ARGV[1], "count", 1, "fence", fence, unpack(ARGV, 3))
+		counts[i] = 1
+	end
+	redis.call("PEXPIRE", KEYS[i], ARGV[2])
+end
+return counts
+`)
+
+// releaseLocksScript releases all the locks held by the given token.
+// It returns a result of releaseScript for every lock.
+var releaseLocksScript = redis.NewScript(-1, `
+local results = {}
+for i = 1, #KEYS do
+	if redis.call("HGET", KEYS[i], "owner") ~= ARGV[1] then
+		results[i] = -1
+	elseif redis.call("HINCRBY", KEYS[i], "count", -1) > 0 then
+		results[i] = 0
+	else
+		redis.call("DEL", KEYS[i])
+		results[i] = 1
+	end
+end
+return results
+`)
+
+// uniqueKeys removes duplicated keys, so every lock in the group is acquired once.
+func uniqueKeys(keys []string) []string {
+	result := make([]string, 0, len(keys))
+	found := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if !found[key] {
+			found[key] = true
+			result = append(result, key)
+		}
+	}
+	return result
+}
+
+// TryAcquireLocks method are makes a single attempt to acquire locks for all the keys at once.
+// Either all the locks are acquired or none of them, so concurrent callers that lock
+// the same keys in a different order cannot deadlock.
+// Group locks do not take places in the fair queues, and are not granted
+// while any of the locks has waiters in its queue, so they cannot overtake the waiters.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - keys              unique lock keys to acquire.
+//  - ttl               a lock timeout (time to live) in milliseconds.
+// Returns: a token of the lock group or empty string if any lock is held by someone else, or error.
+func (c *RedisLock) TryAcquireLocks(correlationId string, keys []string, ttl int64) (token string, err error) {
+	token = cdata.IdGenerator.NextLong()
+	held, err := c.tryAcquireLocks(correlationId, uniqueKeys(keys), token, ttl)
+	if held != "" || err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// tryAcquireLocks makes a single attempt to acquire all the locks.
+// It returns the key of the lock held by someone else or empty string when all the locks were acquired.
+func (c *RedisLock) tryAcquireLocks(correlationId string, keys []string, owner string, ttl int64) (held string, err error) {
+	state, err := c.checkAcquirable(correlationId)
+	if !state {
+		return "", err
+	}
+	if len(keys) == 0 {
+		return "", nil
+	}
+
+	conn := c.client.Get()
+	defer conn.Close()
+
+	args := []interface{}{4 * len(keys)}
+	for _, key := range keys {
+		args = append(args, key)
+	}
+	for _, key := range keys {
+		args = append(args, key+":fence")
+	}
+	for _, key := range keys {
+		args = append(args, key+":queue")
+	}
+	for _, key := range keys {
+		args = append(args, key+":alive")
+	}
+	args = append(args, owner, ttl)
+	args = append(args, c.composeMetadata(correlationId)...)
+
+	counts, err := redis.Ints(acquireLocksScript.Do(conn, args...))
+	if err != nil {
+		return "", err
+	}
+	if counts[0] == 0 {
+		return keys[counts[1]-1], nil
+	}
+
+	for i, key := range keys {
+		if counts[i] == 1 {
+			if err = c.onAcquired(correlationId, key, owner, ttl); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		// Either all the locks are held or none of them
+		for _, key := range keys {
+			c.onReleased(key, owner)
+			c.giveBack(conn, key, owner)
+		}
+		return "", err
+	}
+	return "", nil
+}
+
+// AcquireLocks method are acquires locks for all the keys at once.
+// Either all the locks are acquired or none of them. When any lock is held by someone else
+// it retries until the timeout expires.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - keys              unique lock keys to acquire.
+//  - ttl               a lock timeout (time to live) in milliseconds.
+//  - timeout           a lock acquisition timeout in milliseconds.
+// Returns: a token of the lock group or error.
+func (c *RedisLock) AcquireLocks(correlationId string, keys []string, ttl int64, timeout int64) (token string, err error) {
+	state, err := c.checkAcquirable(correlationId)
+	if !state {
+		return "", err
+	}
+
+	keys = uniqueKeys(keys)
+	token = cdata.IdGenerator.NextLong()
+
+	// Subscribe before the first attempt, so releases in between are not missed
+	released, unsubscribe, err := c.subscribeReleases(keys...)
+	if err != nil {
+		return "", err
+	}
+	defer unsubscribe()
+
+	expireTime := time.Now().Add(time.Duration(timeout) * time.Millisecond)
+
+	for time.Now().Before(expireTime) {
+		held, err := c.tryAcquireLocks(correlationId, keys, token, ttl)
+		if err != nil {
+			return "", err
+		}
+		if held == "" {
+			return token, nil
+		}
+
+		// Wait for the release, but not longer than the held lock is going to live.
+		// Fair locks are handed over to their waiters without notifications, so they are polled
+		wait := time.Until(expireTime)
+		if pttl, err := c.getLockTimeout(held); err == nil && pttl > 0 && pttl < wait {
+			wait = pttl
+		}
+		if retry := time.Duration(c.retryTimeout) * time.Millisecond; c.fair && retry < wait {
+			wait = retry
+		}
+
+		select {
+		case <-released:
+		case <-time.After(wait):
+		}
+	}
+
+	err = cerr.NewConflictError(
+		correlationId,
+		"LOCK_TIMEOUT",
+		"Acquiring locks failed on timeout",
+	).WithDetails("keys", keys)
+	return "", err
+}
+
+// ReleaseLocks method are releases locks acquired together by TryAcquireLocks or AcquireLocks.
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - keys              unique lock keys to release.
+//  - token             a token of the lock group returned on acquisition.
+// Returns: error or nil for success.
+func (c *RedisLock) ReleaseLocks(correlationId string, keys []string, token string) error {
+	state, err := c.checkOpened(correlationId)
+	if !state {
 		return err
 	}
-	// Remove the lock if it matches
-	if keyId == c.lockId {
-		c.client.Send("MULTI")
-		c.client.Send("DEL", key)
-		_, err = c.client.Do("EXEC")
-	} else { // Cancel transaction if it doesn"t match
-		_, err = c.client.Do("UNWATCH")
+
+	keys = uniqueKeys(keys)
+	if len(keys) == 0 {
+		return nil
+	}
+
+	conn := c.client.Get()
+	defer conn.Close()
+
+	args := []interface{}{len(keys)}
+	for _, key := range keys {
+		args = append(args, key)
+	}
+	args = append(args, token)
+
+	results, err := redis.Ints(releaseLocksScript.Do(conn, args...))
+	if err != nil {
+		return err
+	}
+
+	for i, key := range keys {
+		if results[i] != 0 {
+			c.onReleased(key, token)
+		}
+		if results[i] == 1 {
+			c.notifyWaiters(conn, key, token)
+		}
+	}
+	return nil
+}
+
+// ExtendLock method are extends a timeout of the lock acquired by TryAcquireLock or AcquireLock.
+// The timeout is changed only when the lock is still owned by this component.
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - key               a unique lock key to extend.
+//  - ttl               a new lock timeout (time to live) in milliseconds.
+// Returns: true if the lock was extended or false if it is not owned anymore, or error.
+func (c *RedisLock) ExtendLock(correlationId string, key string, ttl int64) (result bool, err error) {
+	c.tokensMx.Lock()
+	token, ok := c.tokens[key]
+	c.tokensMx.Unlock()
+
+	if !ok {
+		return false, nil
+	}
+	return c.ExtendLockWithToken(correlationId, key, token, ttl)
+}
+
+// ExtendLockWithToken method are extends a timeout of the lock held by the given token.
+// The timeout is changed atomically only when the lock is still held by the token.
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - key               a unique lock key to extend.
+//  - token             a lock token returned on acquisition.
+//  - ttl               a new lock timeout (time to live) in milliseconds.
+// Returns: true if the lock was extended or false if it is not held anymore, or error.
+func (c *RedisLock) ExtendLockWithToken(correlationId string, key string, token string, ttl int64) (result bool, err error) {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return false, err
+	}
+
+	conn := c.client.Get()
+	defer conn.Close()
+
+	res, err := redis.Int(extendScript.Do(conn, key, token, ttl))
+	if err != nil {
+		return false, err
+	}
+	return res == 1, nil
+}
+
+// startWatchdog starts a background renewal of the lock held by the token.
+// The lock is renewed every fraction of its ttl until it is released or lost.
+// It returns false when the component is closing and the renewal is not started.
+func (c *RedisLock) startWatchdog(correlationId string, key string, token string, ttl int64) bool {
+	stop := make(chan struct{})
+
+	// The worker is added under the lock, so Close does not wait for workers
+	// while new ones are still being added
+	c.tokensMx.Lock()
+	if c.closing {
+		c.tokensMx.Unlock()
+		return false
+	}
+	c.watchdogs[key+":"+token] = stop
+	c.workers.Add(1)
+	c.tokensMx.Unlock()
+
+	interval := time.Duration(float64(ttl)*c.renewFraction) * time.Millisecond
+	if interval <= 0 {
+		interval = time.Millisecond
+	}
+
+	go func() {
+		defer c.workers.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		renewedAt := time.Now()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				extended, err := c.ExtendLockWithToken(correlationId, key, token, ttl)
+				if err == nil && !extended {
+					// The lock is lost, there is nothing to renew anymore
+					c.onLost(key, token, "Lock is not held anymore")
+					return
+				}
+				if err == nil {
+					renewedAt = time.Now()
+				} else if time.Since(renewedAt) > time.Duration(ttl)*time.Millisecond {
+					c.onLost(key, token, "Lock has expired while Redis was unavailable")
+					return
+				}
+			}
+		}
+	}()
+	return true
+}
+
+// onAcquired registers the lock held by the token and starts its renewal when it is enabled.
+// It refuses the lock when the component is closing, so the caller must give it back.
+func (c *RedisLock) onAcquired(correlationId string, key string, token string, ttl int64) error {
+	info := &HeldLockInfo{
+		Key:           key,
+		Token:         token,
+		CorrelationId: correlationId,
+		AcquiredAt:    time.Now(),
+	}
+	if c.debug {
+		info.Site = acquisitionSite()
+	}
+
+	c.tokensMx.Lock()
+	if c.closing {
+		c.tokensMx.Unlock()
+		return newClosingError(correlationId)
+	}
+	c.held[key+":"+token] = info
+	c.tokensMx.Unlock()
+
+	if c.autoRenew && !c.startWatchdog(correlationId, key, token, ttl) {
+		c.onReleased(key, token)
+		return newClosingError(correlationId)
+	}
+	return nil
+}
+
+// giveBack releases a lock refused by the closing component, so it does not block others until it expires.
+func (c *RedisLock) giveBack(conn redis.Conn, key string, token string) {
+	res, err := redis.Int(releaseScript.Do(conn, key, token))
+	if err == nil && res == 1 {
+		c.notifyWaiters(conn, key, token)
+	}
+}
+
+// onReleased unregisters the lock held by the token and stops its renewal.
+func (c *RedisLock) onReleased(key string, token string) {
+	c.stopWatchdog(key, token)
+
+	c.tokensMx.Lock()
+	delete(c.held, key+":"+token)
+	c.tokensMx.Unlock()
+}
+
+// onLost unregisters the lock held by the token and notifies the lock lost callback.
+func (c *RedisLock) onLost(key string, token string, reason string) {
+	c.onReleased(key, token)
+
+	c.tokensMx.Lock()
+	if c.tokens[key] == token {
+		delete(c.tokens, key)
+	}
+	callback := c.lostCallback
+	c.tokensMx.Unlock()
+
+	if callback != nil {
+		callback(key, token, reason)
+	}
+}
+
+// stopWatchdog stops a background renewal of the lock held by the token.
+func (c *RedisLock) stopWatchdog(key string, token string) {
+	c.tokensMx.Lock()
+	defer c.tokensMx.Unlock()
+
+	if stop, ok := c.watchdogs[key+":"+token]; ok {
+		close(stop)
+		delete(c.watchdogs, key+":"+token)
+	}
+}
+
+// lockInfoScript reads a lock hash with its remaining time to live.
+// It returns nil when the key does not hold a lock.
+var lockInfoScript = redis.NewScript(1, `
+if redis.call("TYPE", KEYS[1]).ok ~= "hash" then
+	return nil
+end
+if redis.call("HEXISTS", KEYS[1], "owner") == 0 then
+	return nil
+end
+return {redis.call("PTTL", KEYS[1]), redis.call("HGETALL", KEYS[1])}
+`)
+
+// IsLocked method are checks if the lock is currently held by anyone.
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - key               a unique lock key to check.
+// Returns: true if the lock is held or false otherwise, or error.
+func (c *RedisLock) IsLocked(correlationId string, key string) (result bool, err error) {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return false, err
+	}
+
+	conn := c.client.Get()
+	defer conn.Close()
+
+	return redis.Bool(conn.Do("HEXISTS", key, "owner"))
+}
+
+// GetLockInfo method are gets information about the lock: its owner, metadata and remaining timeout.
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - key               a unique lock key.
+// Returns: the lock information or nil if the lock is not held, or error.
+func (c *RedisLock) GetLockInfo(correlationId string, key string) (result *LockInfo, err error) {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return nil, err
+	}
+
+	conn := c.client.Get()
+	defer conn.Close()
+
+	return c.readLockInfo(conn, key)
+}
+
+// ListLocks method are gets information about all locks held under keys that match the pattern.
+// The keys are scanned incrementally, so the method is safe to use on large databases.
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - pattern           a glob-style pattern of lock keys, for instance "jobs:*".
+// Returns: a list of held locks or error.
+func (c *RedisLock) ListLocks(correlationId string, pattern string) (result []*LockInfo, err error) {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return nil, err
+	}
+
+	conn := c.client.Get()
+	defer conn.Close()
+
+	result = make([]*LockInfo, 0)
+	cursor := 0
+	for {
+		values, err := redis.Values(conn.Do("SCAN", cursor, "MATCH", pattern, "COUNT", 100))
+		if err != nil {
+			return nil, err
+		}
+		cursor, _ = redis.Int(values[0], nil)
+		keys, _ := redis.Strings(values[1], nil)
+
+		for _, key := range keys {
+			info, err := c.readLockInfo(conn, key)
+			if err != nil {
+				return nil, err
+			}
+			if info != nil {
+				result = append(result, info)
+			}
+		}
+
+		if cursor == 0 {
+			break
+		}
+	}
+	return result, nil
+}
+
+// readLockInfo reads information about the lock stored under the key.
+func (c *RedisLock) readLockInfo(conn redis.Conn, key string) (*LockInfo, error) {
+	values, err := redis.Values(lockInfoScript.Do(conn, key))
+	if err == redis.ErrNil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ttl, _ := redis.Int64(values[0], nil)
+	fields, _ := redis.StringMap(values[1], nil)
+
+	info := &LockInfo{
+		Key:      key,
+		Owner:    fields["owner"],
+		Ttl:      ttl,
+		Metadata: map[string]string{},
+	}
+	info.Count, _ = strconv.Atoi(fields["count"])
+	info.Fence, _ = strconv.ParseInt(fields["fence"], 10, 64)
+
+	for field, value := range fields {
+		if field != "owner" && field != "count" && field != "fence" {
+			info.Metadata[field] = value
+		}
+	}
+	return info, nil
+}
+
+// forceReleaseScript removes a lock regardless of its owner and records the break in the audit list.
+// It returns the previous owner token or nil when the lock is not held.
+var forceReleaseScript = redis.NewScript(2, `
+local owner = redis.call("HGET", KEYS[1], "owner")
+if not owner then
+	return nil
+end
+redis.call("DEL", KEYS[1])
+local record = cjson.decode(ARGV[1])
+record["owner"] = owner
+local json = cjson.encode(record)
+redis.call("LPUSH", KEYS[2], json)
+redis.call("LTRIM", KEYS[2], 0, tonumber(ARGV[2]) - 1)
+return json
+`)
+
+// maxLockBreaks is a maximum number of break records kept per lock.
+const maxLockBreaks = 100
+
+// ForceReleaseLock method are removes the lock regardless of its owner.
+// The break is recorded in "<key>:breaks" list and the previous owner is notified on "lock:lost" channel.
+// It shall be used by operators to clear locks left by hung processes.
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - key               a unique lock key to release.
+//  - reason            an explanation why the lock is broken.
+// Returns: error or nil for success.
+func (c *RedisLock) ForceReleaseLock(correlationId string, key string, reason string) error {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return err
+	}
+
+	record, err := json.Marshal(&LockBreakInfo{
+		Key:           key,
+		Reason:        reason,
+		CorrelationId: correlationId,
+		Host:          c.host,
+		Pid:           c.pid,
+		Service:       c.service,
+		BrokenAt:      time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	conn := c.client.Get()
+	defer conn.Close()
+
+	res, err := redis.String(forceReleaseScript.Do(conn, key, key+":breaks", record, maxLockBreaks))
+	if err == redis.ErrNil {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	conn.Do("PUBLISH", lostLocksChannel, res)
+	c.notifyWaiters(conn, key, "")
+	return nil
+}
+
+// GetLockBreaks method are gets records about forced releases of the lock, the most recent first.
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - key               a unique lock key.
+// Returns: a list of break records or error.
+func (c *RedisLock) GetLockBreaks(correlationId string, key string) (result []*LockBreakInfo, err error) {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return nil, err
+	}
+
+	conn := c.client.Get()
+	defer conn.Close()
+
+	records, err := redis.ByteSlices(conn.Do("LRANGE", key+":breaks", 0, -1))
+	if err != nil {
+		return nil, err
+	}
+
+	result = make([]*LockBreakInfo, 0, len(records))
+	for _, record := range records {
+		var info LockBreakInfo
+		if err := json.Unmarshal(record, &info); err != nil {
+			return nil, err
+		}
+		result = append(result, &info)
+	}
+	return result, nil
+}
+
+// lostLocksChannel is a channel where breaks of the locks are announced.
+// A single channel is used for all the locks, so listeners do not receive
+// unrelated messages published by other applications in the same database.
+const lostLocksChannel = "lock:lost"
+
+// listenLostLocks receives notifications about broken locks and notifies holders of these locks.
+// The subscription is restored after connection failures until the component is closed.
+func (c *RedisLock) listenLostLocks(client *redis.Pool, stop chan struct{}) {
+	defer c.workers.Done()
+
+	for {
+		psc := redis.PubSubConn{Conn: client.Get()}
+		err := psc.Subscribe(lostLocksChannel)
+
+		// The subscription is cancelled on stop, and the connection is closed
+		// by this goroutine, since closing it under a blocked Receive is not safe
+		done := make(chan struct{})
+		if err == nil {
+			go func() {
+				select {
+				case <-stop:
+					psc.Unsubscribe()
+				case <-done:
+				}
+			}()
+		}
+
+		listening := err == nil
+		for listening {
+			switch msg := psc.Receive().(type) {
+			case redis.Message:
+				var info LockBreakInfo
+				if json.Unmarshal(msg.Data, &info) == nil {
+					c.tokensMx.Lock()
+					_, held := c.held[info.Key+":"+info.Owner]
+					c.tokensMx.Unlock()
+
+					if held {
+						c.onLost(info.Key, info.Owner, info.Reason)
+					}
+				}
+			case redis.Subscription:
+				listening = msg.Count > 0
+			case error:
+				listening = false
+			}
+		}
+
+		close(done)
+		psc.Close()
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(time.Duration(c.retryTimeout) * time.Millisecond):
+		}
+	}
+}
+
+// GetHeldLocks method are gets the locks currently held by this component, the oldest first.
+// Returns: a list of held locks.
+func (c *RedisLock) GetHeldLocks() []*HeldLockInfo {
+	c.tokensMx.Lock()
+	result := make([]*HeldLockInfo, 0, len(c.held))
+	for _, info := range c.held {
+		held := *info
+		result = append(result, &held)
+	}
+	c.tokensMx.Unlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].AcquiredAt.Before(result[j].AcquiredAt)
+	})
+	return result
+}
+
+// reportHeldLocks periodically logs the locks held longer than the debug threshold.
+// Every lock is reported once.
+func (c *RedisLock) reportHeldLocks(stop chan struct{}) {
+	defer c.workers.Done()
+
+	threshold := time.Duration(c.debugThreshold) * time.Millisecond
+	interval := threshold / 2
+	if interval < time.Second {
+		interval = time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			c.tokensMx.Lock()
+			for _, info := range c.held {
+				held := time.Since(info.AcquiredAt)
+				if !info.reported && held > threshold {
+					info.reported = true
+					c.logger.Warn(info.CorrelationId, "Lock %s is held for %s, acquired at %s",
+						info.Key, held.Round(time.Millisecond), info.Site)
+				}
+			}
+			c.tokensMx.Unlock()
+		}
+	}
+}
+
+// lockPackage is a prefix of the functions in this package, skipped when the acquisition site is captured.
+var lockPackage = reflect.TypeOf(RedisLock{}).PkgPath() + "."
+
+// acquisitionSite gets the first place in the call stack outside of this package.
+func acquisitionSite() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, lockPackage) {
+			return fmt.Sprintf("%s:%d %s", frame.File, frame.Line, frame.Function)
+		}
+		if !more {
+			return ""
+		}
 	}
-	return err
 }
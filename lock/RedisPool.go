@@ -0,0 +1,52 @@
+package lock
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	ccon "github.com/pip-services3-go/pip-services3-components-go/connect"
+)
+
+// newRedisPool creates a pool of connections to Redis server defined by connection parameters.
+// Parameters:
+//   - connection    connection parameters with host and port or uri.
+//   - dialOpts      options used to dial new connections.
+// Returns: a pool of Redis connections.
+func newRedisPool(connection *ccon.ConnectionParams, dialOpts []redis.DialOption) *redis.Pool {
+	var dial func() (redis.Conn, error)
+	if connection.Uri() != "" {
+		url := connection.Uri()
+		dial = func() (redis.Conn, error) {
+			return redis.DialURL(url, dialOpts...)
+		}
+	} else {
+		host := connection.Host()
+		if host == "" {
+			host = "localhost"
+		}
+		port := strconv.FormatInt(int64(connection.Port()), 10)
+		if port == "0" {
+			port = "6379"
+		}
+		url := host + ":" + port
+		dial = func() (redis.Conn, error) {
+			return redis.Dial("tcp", url, dialOpts...)
+		}
+	}
+
+	return &redis.Pool{
+		Dial:        dial,
+		MaxIdle:     10,
+		IdleTimeout: 5 * time.Minute,
+	}
+}
+
+// pingRedisPool checks that Redis server behind the pool is reachable.
+func pingRedisPool(pool *redis.Pool) error {
+	conn := pool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("PING")
+	return err
+}
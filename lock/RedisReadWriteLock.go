@@ -0,0 +1,407 @@
+package lock
+
+import (
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+	cref "github.com/pip-services3-go/pip-services3-commons-go/refer"
+	cauth "github.com/pip-services3-go/pip-services3-components-go/auth"
+	ccon "github.com/pip-services3-go/pip-services3-components-go/connect"
+)
+
+/*
+RedisReadWriteLock are distributed read/write lock that is implemented based on Redis in-memory database.
+
+The lock allows many concurrent shared (read) holders or a single exclusive (write) holder per key.
+Every holder gets a unique token and a ttl, so locks of crashed holders expire on their own.
+Writers that wait for the lock get priority: new readers are not admitted
+while a writer is waiting, so writers are not starved by a continuous flow of readers.
+
+Configuration parameters:
+
+  - connection(s):
+    - discovery_key:         (optional) a key to retrieve the connection from IDiscovery
+    - host:                  host name or IP address
+    - port:                  port number
+    - uri:                   resource URI or connection string with all parameters in it
+  - credential(s):
+    - store_key:             key to retrieve parameters from credential store
+    - username:              user name (currently is not used)
+    - password:              user password
+  - options:
+    - retry_timeout:         timeout in milliseconds to retry lock acquisition. (Default: 100)
+    - timeout:               connection timeout in milliseconds (default: 30000)
+    - db_num:                database number in Redis  (default 0)
+
+References:
+
+- *:discovery:*:*:1.0        (optional) IDiscovery services to resolve connection
+- *:credential-store:*:*:1.0 (optional) Credential stores to resolve credential
+
+Example:
+
+    lock = NewRedisReadWriteLock();
+    lock.Configure(cconf.NewConfigParamsFromTuples(
+      "host", "localhost",
+      "port", 6379,
+    ));
+
+    err = lock.Open("123")
+      ...
+
+    token, err := lock.AcquireReadLock("123", "report", 10000, 5000)
+    if err == nil {
+    	defer lock.ReleaseReadLock("123", "report", token)
+    	// Reading...
+    }
+
+    token, err = lock.AcquireWriteLock("123", "report", 60000, 30000)
+    if err == nil {
+    	defer lock.ReleaseWriteLock("123", "report", token)
+    	// Migrating...
+    }
+*/
+type RedisReadWriteLock struct {
+	connectionResolver *ccon.ConnectionResolver
+	credentialResolver *cauth.CredentialResolver
+
+	timeout      int
+	retryTimeout int64
+	dbNum        int
+
+	client *redis.Pool
+}
+
+// NewRedisReadWriteLock method are creates a new instance of this lock.
+func NewRedisReadWriteLock() *RedisReadWriteLock {
+	c := &RedisReadWriteLock{
+		connectionResolver: ccon.NewEmptyConnectionResolver(),
+		credentialResolver: cauth.NewEmptyCredentialResolver(),
+		timeout:            30000,
+		retryTimeout:       100,
+		dbNum:              0,
+		client:             nil,
+	}
+	return c
+}
+
+// Configure method are configures component by passing configuration parameters.
+// Parameters:
+//   - config    configuration parameters to be set.
+func (c *RedisReadWriteLock) Configure(config *cconf.ConfigParams) {
+	c.connectionResolver.Configure(config)
+	c.credentialResolver.Configure(config)
+
+	c.timeout = config.GetAsIntegerWithDefault("options.timeout", c.timeout)
+	c.retryTimeout = config.GetAsLongWithDefault("options.retry_timeout", c.retryTimeout)
+	c.dbNum = config.GetAsIntegerWithDefault("options.db_num", c.dbNum)
+	if c.dbNum > 15 || c.dbNum < 0 {
+		c.dbNum = 0
+	}
+}
+
+// SetReferences method are sets references to dependent components.
+// Parameters:
+//   - references 	references to locate the component dependencies.
+func (c *RedisReadWriteLock) SetReferences(references cref.IReferences) {
+	c.connectionResolver.SetReferences(references)
+	c.credentialResolver.SetReferences(references)
+}
+
+// IsOpen method are checks if the component is opened.
+// Returns true if the component has been opened and false otherwise.
+func (c *RedisReadWriteLock) IsOpen() bool {
+	return c.client != nil
+}
+
+// Open method are opens the component.
+// Parameters:
+// 	- correlationId 	(optional) transaction id to trace execution through call chain.
+// Returns: error or nil no errors occured.
+func (c *RedisReadWriteLock) Open(correlationId string) error {
+	connection, err := c.connectionResolver.Resolve(correlationId)
+	if err != nil {
+		return err
+	}
+	if connection == nil {
+		err = cerr.NewConfigError(correlationId, "NO_CONNECTION", "Connection is not configured")
+		return err
+	}
+
+	credential, err := c.credentialResolver.Lookup(correlationId)
+	if err != nil {
+		return err
+	}
+
+	var dialOpts []redis.DialOption = make([]redis.DialOption, 0)
+
+	dialOpts = append(dialOpts, redis.DialConnectTimeout(time.Duration(c.timeout)*time.Millisecond))
+	dialOpts = append(dialOpts, redis.DialDatabase(c.dbNum))
+
+	if credential != nil {
+		dialOpts = append(dialOpts, redis.DialPassword(credential.Password()))
+	}
+
+	client := newRedisPool(connection, dialOpts)
+	if err = pingRedisPool(client); err != nil {
+		client.Close()
+		return err
+	}
+
+	c.client = client
+	return nil
+}
+
+// Close method are closes component and frees used resources.
+// Parameters:
+//  - correlationId 	(optional) transaction id to trace execution through call chain.
+// Retruns: error or nil no errors occured.
+func (c *RedisReadWriteLock) Close(correlationId string) error {
+	if c.client != nil {
+		err := c.client.Close()
+		c.client = nil
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *RedisReadWriteLock) checkOpened(correlationId string) (state bool, err error) {
+	if !c.IsOpen() {
+		err = cerr.NewInvalidStateError(correlationId, "NOT_OPENED", "Connection is not opened")
+		return false, err
+	}
+
+	return true, nil
+}
+
+// The lock is kept in three keys:
+//   - <key>          a writer token with the write lock ttl
+//   - <key>:readers  a sorted set of reader tokens scored by their expiration time
+//   - <key>:writers  a sorted set of waiting writer tokens scored by their expiration time
+// Expiration times are taken from the Redis server clock,
+// so scripts replicate their effects instead of the script itself.
+
+// readLockScript admits a reader when there is neither a writer holding the lock nor a writer waiting for it.
+var readLockScript = redis.NewScript(3, `
+redis.replicate_commands()
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+redis.call("ZREMRANGEBYSCORE", KEYS[2], "-inf", now)
+redis.call("ZREMRANGEBYSCORE", KEYS[3], "-inf", now)
+if redis.call("EXISTS", KEYS[1]) == 1 or redis.call("ZCARD", KEYS[3]) > 0 then
+	return 0
+end
+local ttl = tonumber(ARGV[2])
+redis.call("ZADD", KEYS[2], now + ttl, ARGV[1])
+if redis.call("PTTL", KEYS[2]) < ttl then
+	redis.call("PEXPIRE", KEYS[2], ttl)
+end
+return 1
+`)
+
+// writeLockScript grants the write lock when there are no readers and no other writer.
+// Otherwise a waiting writer is registered to block new readers.
+var writeLockScript = redis.NewScript(3, `
+redis.replicate_commands()
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+redis.call("ZREMRANGEBYSCORE", KEYS[2], "-inf", now)
+redis.call("ZREMRANGEBYSCORE", KEYS[3], "-inf", now)
+if redis.call("EXISTS", KEYS[1]) == 1 or redis.call("ZCARD", KEYS[2]) > 0 then
+	local wait = tonumber(ARGV[3])
+	if wait > 0 then
+		redis.call("ZADD", KEYS[3], now + wait, ARGV[1])
+		if redis.call("PTTL", KEYS[3]) < wait then
+			redis.call("PEXPIRE", KEYS[3], wait)
+		end
+	end
+	return 0
+end
+redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+redis.call("ZREM", KEYS[3], ARGV[1])
+return 1
+`)
+
+// writeUnlockScript removes the write lock only when it is still held by the given token.
+var writeUnlockScript = redis.NewScript(1, `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// TryAcquireReadLock method are makes a single attempt to acquire a shared (read) lock by its key.
+// The lock is granted when it is not held or waited for by a writer.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - key               a unique lock key to acquire.
+//  - ttl               a lock timeout (time to live) in milliseconds.
+// Returns: a lock token or empty string if the lock cannot be acquired, or error.
+func (c *RedisReadWriteLock) TryAcquireReadLock(correlationId string, key string, ttl int64) (token string, err error) {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return "", err
+	}
+
+	conn := c.client.Get()
+	defer conn.Close()
+
+	token = cdata.IdGenerator.NextLong()
+	res, err := redis.Int(readLockScript.Do(conn, key, key+":readers", key+":writers", token, ttl))
+	if err != nil || res == 0 {
+		return "", err
+	}
+	return token, nil
+}
+
+// AcquireReadLock method are acquires a shared (read) lock by its key.
+// When the lock is held or waited for by a writer it retries until the timeout expires.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - key               a unique lock key to acquire.
+//  - ttl               a lock timeout (time to live) in milliseconds.
+//  - timeout           a lock acquisition timeout in milliseconds.
+// Returns: a lock token or error.
+func (c *RedisReadWriteLock) AcquireReadLock(correlationId string, key string, ttl int64, timeout int64) (token string, err error) {
+	expireTime := time.Now().Add(time.Duration(timeout) * time.Millisecond)
+
+	for time.Now().Before(expireTime) {
+		token, err = c.TryAcquireReadLock(correlationId, key, ttl)
+		if token != "" || err != nil {
+			return token, err
+		}
+
+		time.Sleep(time.Duration(c.retryTimeout) * time.Millisecond)
+	}
+
+	err = cerr.NewConflictError(
+		correlationId,
+		"LOCK_TIMEOUT",
+		"Acquiring read lock "+key+" failed on timeout",
+	).WithDetails("key", key)
+	return "", err
+}
+
+// ReleaseReadLock method are releases prevously acquired shared (read) lock by its key and token.
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - key               a unique lock key to release.
+//  - token             a lock token returned on acquisition.
+// Returns: error or nil for success.
+func (c *RedisReadWriteLock) ReleaseReadLock(correlationId string, key string, token string) error {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return err
+	}
+
+	conn := c.client.Get()
+	defer conn.Close()
+
+	_, err = conn.Do("ZREM", key+":readers", token)
+	return err
+}
+
+// TryAcquireWriteLock method are makes a single attempt to acquire an exclusive (write) lock by its key.
+// The lock is granted when it is not held by any reader or writer.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - key               a unique lock key to acquire.
+//  - ttl               a lock timeout (time to live) in milliseconds.
+// Returns: a lock token or empty string if the lock cannot be acquired, or error.
+func (c *RedisReadWriteLock) TryAcquireWriteLock(correlationId string, key string, ttl int64) (token string, err error) {
+	token = cdata.IdGenerator.NextLong()
+	result, err := c.tryAcquireWriteLock(correlationId, key, token, ttl, 0)
+	if !result || err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (c *RedisReadWriteLock) tryAcquireWriteLock(correlationId string, key string, token string,
+	ttl int64, wait int64) (result bool, err error) {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return false, err
+	}
+
+	conn := c.client.Get()
+	defer conn.Close()
+
+	res, err := redis.Int(writeLockScript.Do(conn, key, key+":readers", key+":writers", token, ttl, wait))
+	if err != nil {
+		return false, err
+	}
+	return res == 1, nil
+}
+
+// AcquireWriteLock method are acquires an exclusive (write) lock by its key.
+// While waiting the writer blocks new readers, so it gets the lock as soon as current readers leave.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - key               a unique lock key to acquire.
+//  - ttl               a lock timeout (time to live) in milliseconds.
+//  - timeout           a lock acquisition timeout in milliseconds.
+// Returns: a lock token or error.
+func (c *RedisReadWriteLock) AcquireWriteLock(correlationId string, key string, ttl int64, timeout int64) (token string, err error) {
+	token = cdata.IdGenerator.NextLong()
+	expireTime := time.Now().Add(time.Duration(timeout) * time.Millisecond)
+
+	// Waiting registration outlives a few retries, so it expires when the writer crashes
+	wait := 3 * c.retryTimeout
+
+	for time.Now().Before(expireTime) {
+		result, err := c.tryAcquireWriteLock(correlationId, key, token, ttl, wait)
+		if result || err != nil {
+			if err != nil {
+				c.cancelWaiting(key, token)
+				return "", err
+			}
+			return token, nil
+		}
+
+		time.Sleep(time.Duration(c.retryTimeout) * time.Millisecond)
+	}
+
+	c.cancelWaiting(key, token)
+
+	err = cerr.NewConflictError(
+		correlationId,
+		"LOCK_TIMEOUT",
+		"Acquiring write lock "+key+" failed on timeout",
+	).WithDetails("key", key)
+	return "", err
+}
+
+// cancelWaiting removes a waiting writer registration, so readers are admitted again.
+func (c *RedisReadWriteLock) cancelWaiting(key string, token string) {
+	if !c.IsOpen() {
+		return
+	}
+
+	conn := c.client.Get()
+	defer conn.Close()
+
+	conn.Do("ZREM", key+":writers", token)
+}
+
+// ReleaseWriteLock method are releases prevously acquired exclusive (write) lock by its key and token.
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - key               a unique lock key to release.
+//  - token             a lock token returned on acquisition.
+// Returns: error or nil for success.
+func (c *RedisReadWriteLock) ReleaseWriteLock(correlationId string, key string, token string) error {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return err
+	}
+
+	conn := c.client.Get()
+	defer conn.Close()
+
+	_, err = writeUnlockScript.Do(conn, key, token)
+	return err
+}
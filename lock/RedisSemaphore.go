@@ -0,0 +1,354 @@
+package lock
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+	cref "github.com/pip-services3-go/pip-services3-commons-go/refer"
+	cauth "github.com/pip-services3-go/pip-services3-components-go/auth"
+	ccon "github.com/pip-services3-go/pip-services3-components-go/connect"
+)
+
+/*
+RedisSemaphore are distributed counting semaphore that is implemented based on Redis in-memory database.
+
+Every key has a limited number of permits. Each acquired permit is a lease with a ttl,
+so permits held by crashed holders expire on their own.
+
+Configuration parameters:
+
+  - connection(s):
+    - discovery_key:         (optional) a key to retrieve the connection from IDiscovery
+    - host:                  host name or IP address
+    - port:                  port number
+    - uri:                   resource URI or connection string with all parameters in it
+  - credential(s):
+    - store_key:             key to retrieve parameters from credential store
+    - username:              user name (currently is not used)
+    - password:              user password
+  - permits:
+    - [key]:                 number of permits for the specific key without dots
+    - [n]:                   list of keys with their numbers of permits, for keys that contain dots
+      - key:                 semaphore key
+      - count:               number of permits for the key
+  - options:
+    - permits:               default number of permits per key (default: 1)
+    - retry_timeout:         timeout in milliseconds to retry permit acquisition. (Default: 100)
+    - timeout:               connection timeout in milliseconds (default: 30000)
+    - db_num:                database number in Redis  (default 0)
+
+References:
+
+- *:discovery:*:*:1.0        (optional) IDiscovery services to resolve connection
+- *:credential-store:*:*:1.0 (optional) Credential stores to resolve credential
+
+Example:
+
+    semaphore = NewRedisSemaphore();
+    semaphore.Configure(cconf.NewConfigParamsFromTuples(
+      "host", "localhost",
+      "port", 6379,
+      "permits.vendor_api", 5,
+      "permits.0.key", "vendor.payments",
+      "permits.0.count", 2,
+    ));
+
+    err = semaphore.Open("123")
+      ...
+
+    token, err := semaphore.Acquire("123", "vendor_api", 10000, 3000)
+    if err == nil {
+    	defer semaphore.Release("123", "vendor_api", token)
+    	// Calling the API...
+    }
+*/
+type RedisSemaphore struct {
+	connectionResolver *ccon.ConnectionResolver
+	credentialResolver *cauth.CredentialResolver
+
+	timeout      int
+	retryTimeout int64
+	dbNum        int
+
+	defaultPermits int
+	permits        map[string]int
+	permitsMx      sync.Mutex
+
+	client *redis.Pool
+}
+
+// NewRedisSemaphore method are creates a new instance of this semaphore.
+func NewRedisSemaphore() *RedisSemaphore {
+	c := &RedisSemaphore{
+		connectionResolver: ccon.NewEmptyConnectionResolver(),
+		credentialResolver: cauth.NewEmptyCredentialResolver(),
+		timeout:            30000,
+		retryTimeout:       100,
+		dbNum:              0,
+		defaultPermits:     1,
+		permits:            map[string]int{},
+		client:             nil,
+	}
+	return c
+}
+
+// Configure method are configures component by passing configuration parameters.
+// Parameters:
+//   - config    configuration parameters to be set.
+func (c *RedisSemaphore) Configure(config *cconf.ConfigParams) {
+	c.connectionResolver.Configure(config)
+	c.credentialResolver.Configure(config)
+
+	c.timeout = config.GetAsIntegerWithDefault("options.timeout", c.timeout)
+	c.retryTimeout = config.GetAsLongWithDefault("options.retry_timeout", c.retryTimeout)
+	c.dbNum = config.GetAsIntegerWithDefault("options.db_num", c.dbNum)
+	if c.dbNum > 15 || c.dbNum < 0 {
+		c.dbNum = 0
+	}
+	c.defaultPermits = config.GetAsIntegerWithDefault("options.permits", c.defaultPermits)
+
+	// Keys with dots are split into sections by ConfigParams,
+	// so they are configured as list entries "permits.<n>.key" and "permits.<n>.count"
+	permits := config.GetSection("permits")
+	for _, key := range permits.Keys() {
+		if !strings.Contains(key, ".") {
+			c.SetPermits(key, permits.GetAsIntegerWithDefault(key, c.defaultPermits))
+		}
+	}
+	for _, name := range permits.GetSectionNames() {
+		entry := permits.GetSection(name)
+		if key := entry.GetAsString("key"); key != "" {
+			c.SetPermits(key, entry.GetAsIntegerWithDefault("count", c.defaultPermits))
+		}
+	}
+}
+
+// SetReferences method are sets references to dependent components.
+// Parameters:
+//   - references 	references to locate the component dependencies.
+func (c *RedisSemaphore) SetReferences(references cref.IReferences) {
+	c.connectionResolver.SetReferences(references)
+	c.credentialResolver.SetReferences(references)
+}
+
+// SetPermits method are sets a number of permits for the specific key.
+// Parameters:
+//   - key       a unique semaphore key.
+//   - permits   a maximum number of permits that can be acquired at the same time.
+func (c *RedisSemaphore) SetPermits(key string, permits int) {
+	c.permitsMx.Lock()
+	defer c.permitsMx.Unlock()
+
+	c.permits[key] = permits
+}
+
+// GetPermits method are gets a number of permits for the specific key.
+// Parameters:
+//   - key       a unique semaphore key.
+// Returns: a maximum number of permits that can be acquired at the same time.
+func (c *RedisSemaphore) GetPermits(key string) int {
+	c.permitsMx.Lock()
+	defer c.permitsMx.Unlock()
+
+	if permits, ok := c.permits[key]; ok {
+		return permits
+	}
+	return c.defaultPermits
+}
+
+// IsOpen method are checks if the component is opened.
+// Returns true if the component has been opened and false otherwise.
+func (c *RedisSemaphore) IsOpen() bool {
+	return c.client != nil
+}
+
+// Open method are opens the component.
+// Parameters:
+// 	- correlationId 	(optional) transaction id to trace execution through call chain.
+// Returns: error or nil no errors occured.
+func (c *RedisSemaphore) Open(correlationId string) error {
+	connection, err := c.connectionResolver.Resolve(correlationId)
+	if err != nil {
+		return err
+	}
+	if connection == nil {
+		err = cerr.NewConfigError(correlationId, "NO_CONNECTION", "Connection is not configured")
+		return err
+	}
+
+	credential, err := c.credentialResolver.Lookup(correlationId)
+	if err != nil {
+		return err
+	}
+
+	var dialOpts []redis.DialOption = make([]redis.DialOption, 0)
+
+	dialOpts = append(dialOpts, redis.DialConnectTimeout(time.Duration(c.timeout)*time.Millisecond))
+	dialOpts = append(dialOpts, redis.DialDatabase(c.dbNum))
+
+	if credential != nil {
+		dialOpts = append(dialOpts, redis.DialPassword(credential.Password()))
+	}
+
+	client := newRedisPool(connection, dialOpts)
+	if err = pingRedisPool(client); err != nil {
+		client.Close()
+		return err
+	}
+
+	c.client = client
+	return nil
+}
+
+// Close method are closes component and frees used resources.
+// Parameters:
+//  - correlationId 	(optional) transaction id to trace execution through call chain.
+// Retruns: error or nil no errors occured.
+func (c *RedisSemaphore) Close(correlationId string) error {
+	if c.client != nil {
+		err := c.client.Close()
+		c.client = nil
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *RedisSemaphore) checkOpened(correlationId string) (state bool, err error) {
+	if !c.IsOpen() {
+		err = cerr.NewInvalidStateError(correlationId, "NOT_OPENED", "Connection is not opened")
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Permits are kept in a sorted set of holder tokens scored by their expiration time
+// taken from the Redis server clock. Expired leases are removed on every call.
+
+// semaphoreAcquireScript grants a permit when the number of active leases is below the limit.
+var semaphoreAcquireScript = redis.NewScript(1, `
+redis.replicate_commands()
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now)
+if redis.call("ZCARD", KEYS[1]) >= tonumber(ARGV[3]) then
+	return 0
+end
+local ttl = tonumber(ARGV[2])
+redis.call("ZADD", KEYS[1], now + ttl, ARGV[1])
+if redis.call("PTTL", KEYS[1]) < ttl then
+	redis.call("PEXPIRE", KEYS[1], ttl)
+end
+return 1
+`)
+
+// semaphoreCountScript returns the number of active leases.
+var semaphoreCountScript = redis.NewScript(1, `
+redis.replicate_commands()
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now)
+return redis.call("ZCARD", KEYS[1])
+`)
+
+// TryAcquire method are makes a single attempt to acquire a permit by semaphore key.
+// It returns immediately a positive or negative result.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - key               a unique semaphore key.
+//  - ttl               a permit lease timeout (time to live) in milliseconds.
+// Returns: a permit token or empty string if no permits are available, or error.
+func (c *RedisSemaphore) TryAcquire(correlationId string, key string, ttl int64) (token string, err error) {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return "", err
+	}
+
+	conn := c.client.Get()
+	defer conn.Close()
+
+	token = cdata.IdGenerator.NextLong()
+	res, err := redis.Int(semaphoreAcquireScript.Do(conn, key, token, ttl, c.GetPermits(key)))
+	if err != nil || res == 0 {
+		return "", err
+	}
+	return token, nil
+}
+
+// Acquire method are acquires a permit by semaphore key.
+// When no permits are available it retries until the timeout expires.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - key               a unique semaphore key.
+//  - ttl               a permit lease timeout (time to live) in milliseconds.
+//  - timeout           a permit acquisition timeout in milliseconds.
+// Returns: a permit token or error.
+func (c *RedisSemaphore) Acquire(correlationId string, key string, ttl int64, timeout int64) (token string, err error) {
+	expireTime := time.Now().Add(time.Duration(timeout) * time.Millisecond)
+
+	for time.Now().Before(expireTime) {
+		token, err = c.TryAcquire(correlationId, key, ttl)
+		if token != "" || err != nil {
+			return token, err
+		}
+
+		time.Sleep(time.Duration(c.retryTimeout) * time.Millisecond)
+	}
+
+	err = cerr.NewConflictError(
+		correlationId,
+		"SEMAPHORE_TIMEOUT",
+		"Acquiring permit "+key+" failed on timeout",
+	).WithDetails("key", key)
+	return "", err
+}
+
+// Release method are releases prevously acquired permit by semaphore key and permit token.
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - key               a unique semaphore key.
+//  - token             a permit token returned on acquisition.
+// Returns: error or nil for success.
+func (c *RedisSemaphore) Release(correlationId string, key string, token string) error {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return err
+	}
+
+	conn := c.client.Get()
+	defer conn.Close()
+
+	_, err = conn.Do("ZREM", key, token)
+	return err
+}
+
+// AvailablePermits method are gets a number of permits that can be acquired by semaphore key.
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - key               a unique semaphore key.
+// Returns: a number of available permits or error.
+func (c *RedisSemaphore) AvailablePermits(correlationId string, key string) (result int64, err error) {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return 0, err
+	}
+
+	conn := c.client.Get()
+	defer conn.Close()
+
+	count, err := redis.Int64(semaphoreCountScript.Do(conn, key))
+	if err != nil {
+		return 0, err
+	}
+
+	result = int64(c.GetPermits(key)) - count
+	if result < 0 {
+		result = 0
+	}
+	return result, nil
+}
@@ -0,0 +1,399 @@
+package lock
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+	cref "github.com/pip-services3-go/pip-services3-commons-go/refer"
+	cauth "github.com/pip-services3-go/pip-services3-components-go/auth"
+	ccon "github.com/pip-services3-go/pip-services3-components-go/connect"
+	clock "github.com/pip-services3-go/pip-services3-components-go/lock"
+)
+
+/*
+RedlockLock are distributed lock that is implemented with Redlock algorithm
+over several independent Redis masters.
+
+The lock is acquired only when the majority of the masters grant it
+within the lock validity time, reduced by the time spent on acquisition and the clock drift.
+Locks are always released on all masters.
+
+Configuration parameters:
+
+  - connections:
+    - [node name]:
+      - discovery_key:       (optional) a key to retrieve the connection from IDiscovery
+      - host:                host name or IP address
+      - port:                port number
+      - uri:                 resource URI or connection string with all parameters in it
+  - credential(s):
+    - store_key:             key to retrieve parameters from credential store
+    - username:              user name (currently is not used)
+    - password:              user password
+  - options:
+    - retry_timeout:         timeout in milliseconds to retry lock acquisition, extended by a random delay up to the same timeout. (Default: 100)
+    - timeout:               connection timeout in milliseconds (default: 30000)
+    - node_timeout:          timeout in milliseconds of a single request to a master (default: 50)
+    - drift_factor:          clock drift factor relative to the lock ttl (default: 0.01)
+    - db_num:                database number in Redis  (default 0)
+
+References:
+
+- *:discovery:*:*:1.0        (optional) IDiscovery services to resolve connection
+- *:credential-store:*:*:1.0 (optional) Credential stores to resolve credential
+
+Example:
+
+    lock = NewRedlockLock();
+    lock.Configure(cconf.NewConfigParamsFromTuples(
+      "connections.node1.uri", "redis://redis1:6379",
+      "connections.node2.uri", "redis://redis2:6379",
+      "connections.node3.uri", "redis://redis3:6379",
+    ));
+
+    err = lock.Open("123")
+      ...
+
+    result, err := lock.TryAcquireLock("123", "key1", 3000)
+    if result {
+    	// Processing...
+    }
+    err = lock.ReleaseLock("123", "key1")
+    // Continue...
+*/
+type RedlockLock struct {
+	*clock.Lock
+	connectionResolver *ccon.ConnectionResolver
+	credentialResolver *cauth.CredentialResolver
+
+	timeout      int
+	retryTimeout int64
+	nodeTimeout  int
+	driftFactor  float64
+	dbNum        int
+
+	tokens   map[string]string
+	tokensMx sync.Mutex
+
+	clients []*redis.Pool
+}
+
+// NewRedlockLock method are creates a new instance of this lock.
+func NewRedlockLock() *RedlockLock {
+	c := &RedlockLock{
+		connectionResolver: ccon.NewEmptyConnectionResolver(),
+		credentialResolver: cauth.NewEmptyCredentialResolver(),
+		timeout:            30000,
+		retryTimeout:       100,
+		nodeTimeout:        50,
+		driftFactor:        0.01,
+		dbNum:              0,
+		tokens:             map[string]string{},
+		clients:            nil,
+	}
+	c.Lock = clock.InheritLock(c)
+	return c
+}
+
+// Configure method are configures component by passing configuration parameters.
+// Parameters:
+//   - config    configuration parameters to be set.
+func (c *RedlockLock) Configure(config *cconf.ConfigParams) {
+	c.connectionResolver.Configure(config)
+	c.credentialResolver.Configure(config)
+	c.Lock.Configure(config)
+
+	c.timeout = config.GetAsIntegerWithDefault("options.timeout", c.timeout)
+	c.retryTimeout = config.GetAsLongWithDefault("options.retry_timeout", c.retryTimeout)
+	c.nodeTimeout = config.GetAsIntegerWithDefault("options.node_timeout", c.nodeTimeout)
+	c.driftFactor = config.GetAsDoubleWithDefault("options.drift_factor", c.driftFactor)
+	c.dbNum = config.GetAsIntegerWithDefault("options.db_num", c.dbNum)
+	if c.dbNum > 15 || c.dbNum < 0 {
+		c.dbNum = 0
+	}
+}
+
+// SetReferences method are sets references to dependent components.
+// Parameters:
+//   - references 	references to locate the component dependencies.
+func (c *RedlockLock) SetReferences(references cref.IReferences) {
+	c.connectionResolver.SetReferences(references)
+	c.credentialResolver.SetReferences(references)
+}
+
+// IsOpen method are checks if the component is opened.
+// Returns true if the component has been opened and false otherwise.
+func (c *RedlockLock) IsOpen() bool {
+	return c.clients != nil
+}
+
+// Open method are opens the component.
+// Parameters:
+// 	- correlationId 	(optional) transaction id to trace execution through call chain.
+// Returns: error or nil no errors occured.
+func (c *RedlockLock) Open(correlationId string) error {
+	connections, err := c.connectionResolver.ResolveAll(correlationId)
+	if err != nil {
+		return err
+	}
+	if len(connections) == 0 {
+		err = cerr.NewConfigError(correlationId, "NO_CONNECTION", "Connection is not configured")
+		return err
+	}
+
+	credential, err := c.credentialResolver.Lookup(correlationId)
+	if err != nil {
+		return err
+	}
+
+	var dialOpts []redis.DialOption = make([]redis.DialOption, 0)
+
+	dialOpts = append(dialOpts, redis.DialConnectTimeout(time.Duration(c.timeout)*time.Millisecond))
+	dialOpts = append(dialOpts, redis.DialReadTimeout(time.Duration(c.nodeTimeout)*time.Millisecond))
+	dialOpts = append(dialOpts, redis.DialWriteTimeout(time.Duration(c.nodeTimeout)*time.Millisecond))
+	dialOpts = append(dialOpts, redis.DialDatabase(c.dbNum))
+
+	if credential != nil {
+		dialOpts = append(dialOpts, redis.DialPassword(credential.Password()))
+	}
+
+	clients := make([]*redis.Pool, 0, len(connections))
+	for _, connection := range connections {
+		clients = append(clients, newRedisPool(connection, dialOpts))
+	}
+
+	// Masters may be temporary unavailable, so the component is opened
+	// as long as the majority of them can be reached
+	available := 0
+	var pingErr error
+	for _, client := range clients {
+		if err := pingRedisPool(client); err != nil {
+			pingErr = err
+		} else {
+			available++
+		}
+	}
+	if available < len(clients)/2+1 {
+		for _, client := range clients {
+			client.Close()
+		}
+		connErr := cerr.NewConnectionError(correlationId, "CONNECT_FAILED", "Majority of Redis masters are unavailable")
+		if pingErr != nil {
+			connErr = connErr.WithCause(pingErr)
+		}
+		return connErr
+	}
+
+	c.clients = clients
+	return nil
+}
+
+// Close method are closes component and frees used resources.
+// Parameters:
+//  - correlationId 	(optional) transaction id to trace execution through call chain.
+// Retruns: error or nil no errors occured.
+func (c *RedlockLock) Close(correlationId string) error {
+	if c.clients != nil {
+		var err error
+		for _, client := range c.clients {
+			if closeErr := client.Close(); closeErr != nil {
+				err = closeErr
+			}
+		}
+		c.clients = nil
+
+		c.tokensMx.Lock()
+		c.tokens = map[string]string{}
+		c.tokensMx.Unlock()
+
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *RedlockLock) checkOpened(correlationId string) (state bool, err error) {
+	if !c.IsOpen() {
+		err = cerr.NewInvalidStateError(correlationId, "NOT_OPENED", "Connection is not opened")
+		return false, err
+	}
+
+	return true, nil
+}
+
+// redlockReleaseScript removes a lock on a master only when it is still held by the given token.
+var redlockReleaseScript = redis.NewScript(1, `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// forEachNode executes the action on all masters in parallel
+// and returns the number of masters where it succeeded.
+func (c *RedlockLock) forEachNode(action func(conn redis.Conn) bool) int {
+	var wg sync.WaitGroup
+	var mtx sync.Mutex
+	succeeded := 0
+
+	for _, client := range c.clients {
+		wg.Add(1)
+		go func(client *redis.Pool) {
+			defer wg.Done()
+
+			conn := client.Get()
+			defer conn.Close()
+
+			if action(conn) {
+				mtx.Lock()
+				succeeded++
+				mtx.Unlock()
+			}
+		}(client)
+	}
+
+	wg.Wait()
+	return succeeded
+}
+
+// TryAcquireLock method are makes a single attempt to acquire a lock by its key.
+// It returns immediately a positive or negative result.
+// The acquired lock is owned by the component and can be released by ReleaseLock.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - key               a unique lock key to acquire.
+//  - ttl               a lock timeout (time to live) in milliseconds.
+// Returns: a lock result or error.
+func (c *RedlockLock) TryAcquireLock(correlationId string, key string, ttl int64) (result bool, err error) {
+	token, err := c.TryAcquireLockWithToken(correlationId, key, ttl)
+	if token == "" || err != nil {
+		return false, err
+	}
+
+	c.tokensMx.Lock()
+	c.tokens[key] = token
+	c.tokensMx.Unlock()
+	return true, nil
+}
+
+// TryAcquireLockWithToken method are makes a single attempt to acquire a lock by its key
+// on the majority of masters. Every successful acquisition gets its own unique token.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - key               a unique lock key to acquire.
+//  - ttl               a lock timeout (time to live) in milliseconds.
+// Returns: a lock token or empty string if the lock is held by someone else, or error.
+func (c *RedlockLock) TryAcquireLockWithToken(correlationId string, key string, ttl int64) (token string, err error) {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return "", err
+	}
+
+	token = cdata.IdGenerator.NextLong()
+	start := time.Now()
+
+	acquired := c.forEachNode(func(conn redis.Conn) bool {
+		res, err := redis.String(conn.Do("SET", key, token, "NX", "PX", ttl))
+		return err == nil && res == "OK"
+	})
+
+	// Validity time is reduced by the acquisition time and the clock drift between masters
+	drift := time.Duration(float64(ttl)*c.driftFactor)*time.Millisecond + 2*time.Millisecond
+	validity := time.Duration(ttl)*time.Millisecond - time.Since(start) - drift
+
+	if acquired >= len(c.clients)/2+1 && validity > 0 {
+		return token, nil
+	}
+
+	// Release the lock on all masters, including the ones that failed to respond
+	c.forEachNode(func(conn redis.Conn) bool {
+		_, err := redlockReleaseScript.Do(conn, key, token)
+		return err == nil
+	})
+	return "", nil
+}
+
+// AcquireLockWithToken method are acquires a lock by its key and returns its token.
+// When the lock is held by someone else it retries until the timeout expires.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - key               a unique lock key to acquire.
+//  - ttl               a lock timeout (time to live) in milliseconds.
+//  - timeout           a lock acquisition timeout in milliseconds.
+// Returns: a lock token or error.
+func (c *RedlockLock) AcquireLockWithToken(correlationId string, key string, ttl int64, timeout int64) (token string, err error) {
+	expireTime := time.Now().Add(time.Duration(timeout) * time.Millisecond)
+
+	for time.Now().Before(expireTime) {
+		token, err = c.TryAcquireLockWithToken(correlationId, key, ttl)
+		if token != "" || err != nil {
+			return token, err
+		}
+
+		// Random delays keep competing clients from splitting the votes again in lockstep
+		delay := c.retryTimeout
+		if delay > 0 {
+			delay += rand.Int63n(delay)
+		}
+		time.Sleep(time.Duration(delay) * time.Millisecond)
+	}
+
+	err = cerr.NewConflictError(
+		correlationId,
+		"LOCK_TIMEOUT",
+		"Acquiring lock "+key+" failed on timeout",
+	).WithDetails("key", key)
+	return "", err
+}
+
+// ReleaseLock method are releases prevously acquired lock by its key.
+// Only locks acquired by TryAcquireLock or AcquireLock of this component are released.
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - key               a unique lock key to release.
+// Returns: error or nil for success.
+func (c *RedlockLock) ReleaseLock(correlationId string, key string) error {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return err
+	}
+
+	c.tokensMx.Lock()
+	token, ok := c.tokens[key]
+	delete(c.tokens, key)
+	c.tokensMx.Unlock()
+
+	if !ok {
+		return nil
+	}
+	return c.ReleaseLockWithToken(correlationId, key, token)
+}
+
+// ReleaseLockWithToken method are releases prevously acquired lock by its key and token on all masters.
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - key               a unique lock key to release.
+//  - token             a lock token returned on acquisition.
+// Returns: error or nil for success.
+func (c *RedlockLock) ReleaseLockWithToken(correlationId string, key string, token string) error {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return err
+	}
+
+	released := c.forEachNode(func(conn redis.Conn) bool {
+		_, err := redlockReleaseScript.Do(conn, key, token)
+		return err == nil
+	})
+
+	// Locks on unavailable masters expire on their own
+	if released < len(c.clients)/2+1 {
+		return cerr.NewConnectionError(correlationId, "RELEASE_FAILED", "Failed to release lock "+key+" on majority of Redis masters").
+			WithDetails("key", key)
+	}
+	return nil
+}
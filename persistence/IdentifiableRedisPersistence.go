@@ -0,0 +1,302 @@
+package persistence
+
+import (
+	"reflect"
+
+	cconv "github.com/pip-services3-go/pip-services3-commons-go/convert"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+)
+
+/*
+IdentifiableRedisPersistence are abstract persistence component that stores data in Redis
+and implements a number of CRUD operations over data items with unique ids.
+The data items must have "id" JSON field.
+
+In basic scenarios child structs shall only override GetPageByFilter,
+GetListByFilter or DeleteByFilter operations with specific filter function.
+All other operations can be used out of the box.
+
+Configuration parameters:
+
+  - collection:                  (optional) collection name, used as a key prefix
+  - connection(s):
+    - discovery_key:             (optional) a key to retrieve the connection from IDiscovery
+    - host:                      host name or IP address
+    - port:                      port number
+    - uri:                       resource URI or connection string with all parameters in it
+  - credential(s):
+    - store_key:                 key to retrieve parameters from credential store
+    - username:                  user name (currently is not used)
+    - password:                  user password
+  - options:
+    - max_page_size:             maximum number of items returned in a single page (default: 100)
+    - timeout:                   connection timeout in milliseconds (default: 30000)
+    - db_num:                    database number in Redis  (default 0)
+    - cluster:                   enable redis cluster
+
+References:
+
+- *:logger:*:*:1.0           (optional) ILogger components to pass log messages
+- *:discovery:*:*:1.0        (optional) IDiscovery services to resolve connection
+- *:credential-store:*:*:1.0 (optional) Credential stores to resolve credential
+
+Example:
+
+    type MyRedisPersistence struct {
+    	*IdentifiableRedisPersistence
+    }
+
+    func NewMyRedisPersistence() *MyRedisPersistence {
+    	proto := reflect.TypeOf(MyData{})
+    	return &MyRedisPersistence{IdentifiableRedisPersistence: NewIdentifiableRedisPersistence(proto, "mydata")}
+    }
+
+    persistence := NewMyRedisPersistence()
+    persistence.Configure(cconf.NewConfigParamsFromTuples(
+      "connection.host", "localhost",
+      "connection.port", 6379,
+    ))
+
+    err := persistence.Open("123")
+      ...
+
+    item, err := persistence.Create("123", MyData{Id: "1", Name: "ABC"})
+    item, err = persistence.GetOneById("123", "1")
+    // Result: { Id: "1", Name: "ABC" }
+*/
+type IdentifiableRedisPersistence struct {
+	*RedisPersistence
+}
+
+// NewIdentifiableRedisPersistence method are creates a new instance of the persistence component.
+// Parameters:
+//  - proto         a type of stored data objects.
+//  - collection    (optional) a collection name.
+func NewIdentifiableRedisPersistence(proto reflect.Type, collection string) *IdentifiableRedisPersistence {
+	c := &IdentifiableRedisPersistence{
+		RedisPersistence: NewRedisPersistence(proto, collection),
+	}
+	return c
+}
+
+// GetListByIds method are gets a list of data items retrieved by given unique ids.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - ids               ids of data items to be retrieved
+// Returns: a data list or error.
+func (c *IdentifiableRedisPersistence) GetListByIds(correlationId string, ids []interface{}) (items []interface{}, err error) {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return nil, err
+	}
+
+	keys := make([]string, len(ids))
+	for index, id := range ids {
+		keys[index] = cconv.StringConverter.ToString(id)
+	}
+
+	_, items, err = c.readItems(correlationId, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Logger.Trace(correlationId, "Retrieved %d items from %s", len(items), c.CollectionName)
+	return items, nil
+}
+
+// GetOneById method are gets a data item by its unique id.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - id                an id of data item to be retrieved.
+// Returns: a found data item, nil if nothing was found, or error.
+func (c *IdentifiableRedisPersistence) GetOneById(correlationId string, id interface{}) (item interface{}, err error) {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return nil, err
+	}
+
+	key := cconv.StringConverter.ToString(id)
+	_, items, err := c.readItems(correlationId, []string{key})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(items) == 0 {
+		c.Logger.Trace(correlationId, "Nothing found from %s with id = %s", c.CollectionName, key)
+		return nil, nil
+	}
+
+	c.Logger.Trace(correlationId, "Retrieved from %s with id = %s", c.CollectionName, key)
+	return items[0], nil
+}
+
+// Create method are creates a data item. If the item has no id, it is generated.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - item              an item to be created.
+// Returns: a created item or error.
+func (c *IdentifiableRedisPersistence) Create(correlationId string, item interface{}) (result interface{}, err error) {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return nil, err
+	}
+
+	newItem, err := c.ConvertFromPublic(item)
+	if err != nil {
+		return nil, err
+	}
+
+	if cconv.StringConverter.ToString(newItem["id"]) == "" {
+		newItem["id"] = cdata.IdGenerator.NextLong()
+	}
+
+	return c.RedisPersistence.Create(correlationId, newItem)
+}
+
+// Set method are sets a data item. If the data item exists it updates it,
+// otherwise it create a new data item. If the item has no id, it is generated.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - item              an item to be set.
+// Returns: an updated item or error.
+func (c *IdentifiableRedisPersistence) Set(correlationId string, item interface{}) (result interface{}, err error) {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return nil, err
+	}
+
+	newItem, err := c.ConvertFromPublic(item)
+	if err != nil {
+		return nil, err
+	}
+
+	id := cconv.StringConverter.ToString(newItem["id"])
+	if id == "" {
+		id = cdata.IdGenerator.NextLong()
+		newItem["id"] = id
+	}
+
+	newItem, err = c.save(correlationId, id, func(old map[string]interface{}) (map[string]interface{}, error) {
+		return newItem, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c.Logger.Trace(correlationId, "Set in %s with id = %s", c.CollectionName, id)
+	return c.toPublic(newItem)
+}
+
+// Update method are updates a data item.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - item              an item to be updated.
+// Returns: an updated item, nil if it wasn't found, or error.
+func (c *IdentifiableRedisPersistence) Update(correlationId string, item interface{}) (result interface{}, err error) {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return nil, err
+	}
+
+	newItem, err := c.ConvertFromPublic(item)
+	if err != nil {
+		return nil, err
+	}
+
+	id := cconv.StringConverter.ToString(newItem["id"])
+	if id == "" {
+		return nil, nil
+	}
+
+	newItem, err = c.save(correlationId, id, func(old map[string]interface{}) (map[string]interface{}, error) {
+		if old == nil {
+			return nil, nil
+		}
+		return newItem, nil
+	})
+	if err != nil || newItem == nil {
+		return nil, err
+	}
+
+	c.Logger.Trace(correlationId, "Updated in %s with id = %s", c.CollectionName, id)
+	return c.toPublic(newItem)
+}
+
+// UpdatePartially method are updates only few selected fields in a data item.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - id                an id of data item to be updated.
+//  - data              a map with fields to be updated by their JSON names.
+// Returns: an updated item, nil if it wasn't found, or error.
+func (c *IdentifiableRedisPersistence) UpdatePartially(correlationId string, id interface{}, data *cdata.AnyValueMap) (result interface{}, err error) {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return nil, err
+	}
+
+	key := cconv.StringConverter.ToString(id)
+	newItem, err := c.save(correlationId, key, func(old map[string]interface{}) (map[string]interface{}, error) {
+		if old == nil {
+			return nil, nil
+		}
+
+		for field, value := range data.Value() {
+			old[field] = value
+		}
+		old["id"] = key
+		return old, nil
+	})
+	if err != nil || newItem == nil {
+		return nil, err
+	}
+
+	c.Logger.Trace(correlationId, "Updated partially in %s with id = %s", c.CollectionName, key)
+	return c.toPublic(newItem)
+}
+
+// DeleteById method are deleted a data item by it's unique id.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - id                an id of the item to be deleted
+// Returns: a deleted item, nil if it wasn't found, or error.
+func (c *IdentifiableRedisPersistence) DeleteById(correlationId string, id interface{}) (result interface{}, err error) {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return nil, err
+	}
+
+	key := cconv.StringConverter.ToString(id)
+	oldItem, err := c.remove(correlationId, key)
+	if err != nil || oldItem == nil {
+		return nil, err
+	}
+
+	c.Logger.Trace(correlationId, "Deleted from %s with id = %s", c.CollectionName, key)
+	return c.toPublic(oldItem)
+}
+
+// DeleteByIds method are deletes multiple data items by their unique ids.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - ids               ids of data items to be deleted.
+// Returns: error or nil for success.
+func (c *IdentifiableRedisPersistence) DeleteByIds(correlationId string, ids []interface{}) error {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return err
+	}
+
+	count := 0
+	for _, id := range ids {
+		oldItem, err := c.remove(correlationId, cconv.StringConverter.ToString(id))
+		if err != nil {
+			return err
+		}
+		if oldItem != nil {
+			count++
+		}
+	}
+
+	c.Logger.Trace(correlationId, "Deleted %d items from %s", count, c.CollectionName)
+	return nil
+}
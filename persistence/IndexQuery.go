@@ -0,0 +1,80 @@
+package persistence
+
+const (
+	// SetIndex keeps ids of objects in a set per field value and supports equality conditions.
+	SetIndex = "set"
+	// SortedIndex keeps ids of objects in a sorted set scored by field value
+	// and supports range conditions and sorting.
+	SortedIndex = "sorted"
+)
+
+/*
+IndexQuery are conditions on indexed fields used to retrieve data items without reading all of them.
+All conditions must be met.
+
+See RedisPersistence.GetPageByQuery
+*/
+type IndexQuery struct {
+	// Equals are values of fields with set indexes.
+	Equals map[string]interface{}
+	// Ranges are ranges of values of fields with sorted indexes.
+	Ranges map[string]*IndexRange
+	// Sort is a field with sorted index to sort items by. Items are sorted by id when it is empty.
+	// Items without a value of the field follow sorted items in both directions, ordered by id.
+	Sort string
+	// Descending is true to sort items in descending order.
+	Descending bool
+}
+
+/*
+IndexRange are inclusive range of values of a field with sorted index.
+Numbers, booleans and RFC3339 times are supported.
+
+See IndexQuery
+*/
+type IndexRange struct {
+	// Min is a minimum value or nil for no lower bound.
+	Min interface{}
+	// Max is a maximum value or nil for no upper bound.
+	Max interface{}
+}
+
+// NewIndexQuery method are creates a new empty query.
+func NewIndexQuery() *IndexQuery {
+	return &IndexQuery{
+		Equals: map[string]interface{}{},
+		Ranges: map[string]*IndexRange{},
+	}
+}
+
+// WithEqual method are adds a condition on a field with set index.
+// Parameters:
+//  - field     a JSON name of the field.
+//  - value     a value the field must have.
+// Returns: this query.
+func (c *IndexQuery) WithEqual(field string, value interface{}) *IndexQuery {
+	c.Equals[field] = value
+	return c
+}
+
+// WithRange method are adds a condition on a field with sorted index.
+// Parameters:
+//  - field     a JSON name of the field.
+//  - min       a minimum value or nil for no lower bound.
+//  - max       a maximum value or nil for no upper bound.
+// Returns: this query.
+func (c *IndexQuery) WithRange(field string, min interface{}, max interface{}) *IndexQuery {
+	c.Ranges[field] = &IndexRange{Min: min, Max: max}
+	return c
+}
+
+// WithSort method are sets a field with sorted index to sort items by.
+// Parameters:
+//  - field         a JSON name of the field.
+//  - descending    true to sort items in descending order.
+// Returns: this query.
+func (c *IndexQuery) WithSort(field string, descending bool) *IndexQuery {
+	c.Sort = field
+	c.Descending = descending
+	return c
+}
@@ -0,0 +1,1077 @@
+package persistence
+
+import (
+	"encoding/json"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis"
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cconv "github.com/pip-services3-go/pip-services3-commons-go/convert"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+	cref "github.com/pip-services3-go/pip-services3-commons-go/refer"
+	clog "github.com/pip-services3-go/pip-services3-components-go/log"
+	redisconn "github.com/pip-services3-go/pip-services3-redis-go/connect"
+)
+
+/*
+RedisPersistence are abstract persistence component that stores data in Redis.
+
+Every object is stored as JSON under "{<collection>}:item:<id>" key, where id is taken from
+its "id" JSON field, and ids of all objects are kept in "{<collection>}:ids" set.
+Objects have their own key namespace, so their ids can't clash with service keys of the collection.
+The collection name is a hash tag, so all keys of a collection are kept in a single
+cluster slot. All writes are performed in transactions with optimistic locking,
+so concurrent writers can't overwrite each other's changes.
+
+Redis can't query the stored objects, so filters and sorting are functions
+applied to the objects in memory, like in memory persistence.
+To avoid reading all objects, indexes on fields can be declared with EnsureIndex:
+  - set indexes keep "{<collection>}:idx:set:<field length>:<field>:<value>" sets of ids per field value.
+    Every element of array fields is indexed. The field name is prefixed with its length,
+    so fields and values that contain colons can't produce the same keys.
+  - sorted indexes keep "{<collection>}:idx:sorted:<field>" sorted sets of ids scored by
+    numeric, boolean or RFC3339 time field values.
+Indexes are updated in the same transactions as objects and used by GetPageByQuery
+and GetCountByQuery to filter, sort and page objects in Redis.
+
+This is the most basic persistence component that is only
+able to store data items of any type. Specific CRUD operations
+over the data items must be implemented in child structs by
+accessing Client or using the helper methods.
+
+Configuration parameters:
+
+  - collection:                  (optional) collection name, used as a key prefix
+  - connection(s):
+    - discovery_key:             (optional) a key to retrieve the connection from IDiscovery
+    - host:                      host name or IP address
+    - port:                      port number
+    - uri:                       resource URI or connection string with all parameters in it
+  - credential(s):
+    - store_key:                 key to retrieve parameters from credential store
+    - username:                  user name (currently is not used)
+    - password:                  user password
+  - options:
+    - max_page_size:             maximum number of items returned in a single page (default: 100)
+    - timeout:                   connection timeout in milliseconds (default: 30000)
+    - db_num:                    database number in Redis  (default 0)
+    - cluster:                   enable redis cluster
+
+References:
+
+- *:logger:*:*:1.0           (optional) ILogger components to pass log messages
+- *:discovery:*:*:1.0        (optional) IDiscovery services to resolve connection
+- *:credential-store:*:*:1.0 (optional) Credential stores to resolve credential
+
+Example:
+
+    type MyRedisPersistence struct {
+    	*RedisPersistence
+    }
+
+    func NewMyRedisPersistence() *MyRedisPersistence {
+    	proto := reflect.TypeOf(MyData{})
+    	return &MyRedisPersistence{RedisPersistence: NewRedisPersistence(proto, "mydata")}
+    }
+
+    func (c *MyRedisPersistence) GetPageByName(correlationId string, name string,
+    	paging *cdata.PagingParams) (page *cdata.DataPage, err error) {
+    	filter := func(item interface{}) bool {
+    		return item.(MyData).Name == name
+    	}
+    	return c.RedisPersistence.GetPageByFilter(correlationId, filter, paging, nil, nil)
+    }
+
+    // Indexes shall be declared before the component is opened
+    persistence.EnsureIndex("name", SetIndex)
+    persistence.EnsureIndex("create_time", SortedIndex)
+
+    query := NewIndexQuery().WithEqual("name", "ABC").WithSort("create_time", true)
+    page, err := persistence.GetPageByQuery("123", query, cdata.NewPagingParams(0, 10, true))
+*/
+type RedisPersistence struct {
+	Prototype          reflect.Type
+	CollectionName     string
+	Client             redis.UniversalClient
+	Logger             *clog.CompositeLogger
+	connectionResolver *redisconn.RedisConnectionResolver
+	maxPageSize        int64
+	indexes            map[string]string
+}
+
+// maxTxRetries is a number of attempts to write an object changed by another writer.
+const maxTxRetries = 10
+
+// readBatchSize is a number of objects read from Redis in a single request.
+const readBatchSize = 100
+
+// NewRedisPersistence method are creates a new instance of the persistence component.
+// Parameters:
+//  - proto         a type of stored data objects.
+//  - collection    (optional) a collection name.
+func NewRedisPersistence(proto reflect.Type, collection string) *RedisPersistence {
+	c := &RedisPersistence{
+		Prototype:          proto,
+		CollectionName:     collection,
+		Logger:             clog.NewCompositeLogger(),
+		connectionResolver: redisconn.NewRedisConnectionResolver(),
+		maxPageSize:        100,
+		indexes:            map[string]string{},
+		Client:             nil,
+	}
+	return c
+}
+
+// Configure method are configures component by passing configuration parameters.
+// Parameters:
+//   - config    configuration parameters to be set.
+func (c *RedisPersistence) Configure(config *cconf.ConfigParams) {
+	c.connectionResolver.Configure(config)
+	c.Logger.Configure(config)
+
+	c.CollectionName = config.GetAsStringWithDefault("collection", c.CollectionName)
+	c.maxPageSize = config.GetAsLongWithDefault("options.max_page_size", c.maxPageSize)
+}
+
+// SetReferences method are sets references to dependent components.
+// Parameters:
+//   - references 	references to locate the component dependencies.
+func (c *RedisPersistence) SetReferences(references cref.IReferences) {
+	c.connectionResolver.SetReferences(references)
+	c.Logger.SetReferences(references)
+}
+
+// IsOpen method are checks if the component is opened.
+// Returns true if the component has been opened and false otherwise.
+func (c *RedisPersistence) IsOpen() bool {
+	return c.Client != nil
+}
+
+// Open method are opens the component.
+// Parameters:
+// 	- correlationId 	(optional) transaction id to trace execution through call chain.
+// Returns: error or nil no errors occured.
+func (c *RedisPersistence) Open(correlationId string) error {
+	if c.CollectionName == "" {
+		return cerr.NewConfigError(correlationId, "NO_COLLECTION", "Collection is not defined")
+	}
+
+	client, err := c.connectionResolver.Connect(correlationId)
+	if err != nil {
+		return err
+	}
+
+	c.Client = client
+	c.Logger.Debug(correlationId, "Opened Redis persistence for %s", c.CollectionName)
+	return nil
+}
+
+// Close method are closes component and frees used resources.
+// Parameters:
+//  - correlationId 	(optional) transaction id to trace execution through call chain.
+// Retruns: error or nil no errors occured.
+func (c *RedisPersistence) Close(correlationId string) error {
+	if c.Client != nil {
+		err := c.Client.Close()
+		c.Client = nil
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *RedisPersistence) checkOpened(correlationId string) (state bool, err error) {
+	if !c.IsOpen() {
+		err = cerr.NewInvalidStateError(correlationId, "NOT_OPENED", "Connection is not opened")
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Clear method are clears component state by removing all stored objects.
+// Parameters:
+//  - correlationId 	(optional) transaction id to trace execution through call chain.
+// Returns: error or nil no errors occured.
+func (c *RedisPersistence) Clear(correlationId string) error {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return err
+	}
+
+	ids, err := c.Client.SMembers(c.idsKey()).Result()
+	if err != nil {
+		return err
+	}
+
+	for start := 0; start < len(ids); start += readBatchSize {
+		end := start + readBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		keys := make([]string, 0, end-start)
+		for _, id := range ids[start:end] {
+			keys = append(keys, c.itemKey(id))
+		}
+		if err = c.Client.Del(keys...).Err(); err != nil {
+			return err
+		}
+	}
+
+	if err = c.deleteIndexes(); err != nil {
+		return err
+	}
+	return c.Client.Del(c.idsKey()).Err()
+}
+
+// keyPrefix gets a prefix of all collection keys with the collection name as a hash tag.
+func (c *RedisPersistence) keyPrefix() string {
+	return "{" + c.CollectionName + "}:"
+}
+
+func (c *RedisPersistence) idsKey() string {
+	return c.keyPrefix() + "ids"
+}
+
+func (c *RedisPersistence) itemKey(id string) string {
+	return c.keyPrefix() + "item:" + id
+}
+
+// ConvertToPublic method are converts object value from JSON stored in Redis into public format.
+// Parameters:
+//  - value     a JSON of the stored object.
+// Returns: converted object of the prototype type or error.
+func (c *RedisPersistence) ConvertToPublic(value string) (interface{}, error) {
+	if c.Prototype.Kind() == reflect.Ptr {
+		item := reflect.New(c.Prototype.Elem())
+		err := json.Unmarshal([]byte(value), item.Interface())
+		return item.Interface(), err
+	}
+
+	item := reflect.New(c.Prototype)
+	err := json.Unmarshal([]byte(value), item.Interface())
+	return item.Elem().Interface(), err
+}
+
+// ConvertFromPublic method are converts object value from public format into fields stored in Redis.
+// Parameters:
+//  - value     an object in public format.
+// Returns: object fields by their JSON names or error.
+func (c *RedisPersistence) ConvertFromPublic(value interface{}) (map[string]interface{}, error) {
+	buffer, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var result map[string]interface{}
+	if err = json.Unmarshal(buffer, &result); err != nil {
+		return nil, err
+	}
+	if result == nil {
+		result = map[string]interface{}{}
+	}
+	return result, nil
+}
+
+// readItems reads objects by their ids in batches. Objects that don't exist are skipped.
+func (c *RedisPersistence) readItems(correlationId string, ids []string) (foundIds []string, items []interface{}, err error) {
+	foundIds = []string{}
+	items = []interface{}{}
+
+	for start := 0; start < len(ids); start += readBatchSize {
+		end := start + readBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		keys := make([]string, 0, end-start)
+		for _, id := range ids[start:end] {
+			keys = append(keys, c.itemKey(id))
+		}
+
+		values, err := c.Client.MGet(keys...).Result()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for index, value := range values {
+			data, ok := value.(string)
+			if !ok {
+				continue
+			}
+
+			item, err := c.ConvertToPublic(data)
+			if err != nil {
+				return nil, nil, err
+			}
+			foundIds = append(foundIds, ids[start+index])
+			items = append(items, item)
+		}
+	}
+	return foundIds, items, nil
+}
+
+// readByFilter reads all objects and returns the ones that match the filter.
+func (c *RedisPersistence) readByFilter(correlationId string, filter func(interface{}) bool) (ids []string, items []interface{}, err error) {
+	ids, err = c.Client.SMembers(c.idsKey()).Result()
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Strings(ids)
+
+	ids, items, err = c.readItems(correlationId, ids)
+	if err != nil || filter == nil {
+		return ids, items, err
+	}
+
+	filteredIds := []string{}
+	filteredItems := []interface{}{}
+	for index, item := range items {
+		if filter(item) {
+			filteredIds = append(filteredIds, ids[index])
+			filteredItems = append(filteredItems, item)
+		}
+	}
+	return filteredIds, filteredItems, nil
+}
+
+// watch reads the stored object fields and calls the function within a transaction
+// that fails when the object is changed by another writer. Failed transactions are retried.
+func (c *RedisPersistence) watch(id string, fn func(tx *redis.Tx, old map[string]interface{}) error) error {
+	key := c.itemKey(id)
+
+	var err error
+	for retry := 0; retry < maxTxRetries; retry++ {
+		err = c.Client.Watch(func(tx *redis.Tx) error {
+			var old map[string]interface{}
+			data, err := tx.Get(key).Result()
+			if err != nil && err != redis.Nil {
+				return err
+			}
+			if err == nil {
+				if err = json.Unmarshal([]byte(data), &old); err != nil {
+					return err
+				}
+			}
+			return fn(tx, old)
+		}, key)
+
+		if err != redis.TxFailedErr {
+			return err
+		}
+	}
+	return err
+}
+
+// save atomically replaces the stored object with the fields returned by the update function.
+// When the function returns nil fields nothing is written.
+func (c *RedisPersistence) save(correlationId string, id string,
+	update func(old map[string]interface{}) (map[string]interface{}, error)) (item map[string]interface{}, err error) {
+	err = c.watch(id, func(tx *redis.Tx, old map[string]interface{}) error {
+		item, err = update(old)
+		if err != nil || item == nil {
+			return err
+		}
+
+		data, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Pipelined(func(pipe redis.Pipeliner) error {
+			pipe.Set(c.itemKey(id), data, 0)
+			pipe.SAdd(c.idsKey(), id)
+			c.writeIndexes(pipe, id, old, item)
+			return nil
+		})
+		return err
+	})
+	return item, err
+}
+
+// remove atomically deletes the stored object and returns its fields, or nil if it didn't exist.
+func (c *RedisPersistence) remove(correlationId string, id string) (item map[string]interface{}, err error) {
+	err = c.watch(id, func(tx *redis.Tx, old map[string]interface{}) error {
+		item = old
+		if old == nil {
+			return nil
+		}
+
+		_, err = tx.Pipelined(func(pipe redis.Pipeliner) error {
+			pipe.Del(c.itemKey(id))
+			pipe.SRem(c.idsKey(), id)
+			c.writeIndexes(pipe, id, old, nil)
+			return nil
+		})
+		return err
+	})
+	return item, err
+}
+
+// toPublic converts stored object fields into public format.
+func (c *RedisPersistence) toPublic(item map[string]interface{}) (interface{}, error) {
+	if item == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+	return c.ConvertToPublic(string(data))
+}
+
+// GetPageByFilter method are gets a page of data items retrieved by a given filter and sorted according to sort parameters.
+// This method shall be called by a public getPageByFilter method from child struct that
+// receives FilterParams and converts them into a filter function.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - filter            (optional) a filter function to filter items
+//  - paging            (optional) paging parameters
+//  - sortFunc          (optional) sorting compare function func Less (a, b interface{}) bool  see sort.Interface Less function
+//  - sel               (optional) projection parameters (not used yet)
+// Returns: a data page or error.
+func (c *RedisPersistence) GetPageByFilter(correlationId string, filter func(interface{}) bool,
+	paging *cdata.PagingParams, sortFunc func(a, b interface{}) bool, sel func(in interface{}) (out interface{})) (page *cdata.DataPage, err error) {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return nil, err
+	}
+
+	_, items, err := c.readByFilter(correlationId, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if sortFunc != nil {
+		sort.SliceStable(items, func(i, j int) bool { return sortFunc(items[i], items[j]) })
+	}
+
+	if paging == nil {
+		paging = cdata.NewEmptyPagingParams()
+	}
+	skip := paging.GetSkip(-1)
+	take := paging.GetTake(c.maxPageSize)
+
+	var total int64
+	if paging.Total {
+		total = int64(len(items))
+	}
+	if skip > 0 {
+		if skip > int64(len(items)) {
+			skip = int64(len(items))
+		}
+		items = items[skip:]
+	}
+	if int64(len(items)) > take {
+		items = items[:take]
+	}
+
+	if sel != nil {
+		for index, item := range items {
+			items[index] = sel(item)
+		}
+	}
+
+	c.Logger.Trace(correlationId, "Retrieved %d items from %s", len(items), c.CollectionName)
+
+	if paging.Total {
+		return cdata.NewDataPage(&total, items), nil
+	}
+	return cdata.NewDataPage(nil, items), nil
+}
+
+// GetListByFilter method are gets a list of data items retrieved by a given filter and sorted according to sort parameters.
+// This method shall be called by a public getListByFilter method from child struct that
+// receives FilterParams and converts them into a filter function.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - filter            (optional) a filter function to filter items
+//  - sortFunc          (optional) sorting compare function func Less (a, b interface{}) bool  see sort.Interface Less function
+//  - sel               (optional) projection parameters (not used yet)
+// Returns: a data list or error.
+func (c *RedisPersistence) GetListByFilter(correlationId string, filter func(interface{}) bool,
+	sortFunc func(a, b interface{}) bool, sel func(in interface{}) (out interface{})) (items []interface{}, err error) {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return nil, err
+	}
+
+	_, items, err = c.readByFilter(correlationId, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if sortFunc != nil {
+		sort.SliceStable(items, func(i, j int) bool { return sortFunc(items[i], items[j]) })
+	}
+
+	if sel != nil {
+		for index, item := range items {
+			items[index] = sel(item)
+		}
+	}
+
+	c.Logger.Trace(correlationId, "Retrieved %d items from %s", len(items), c.CollectionName)
+	return items, nil
+}
+
+// GetCountByFilter method are gets a number of data items retrieved by a given filter.
+// This method shall be called by a public getCountByFilter method from child struct that
+// receives FilterParams and converts them into a filter function.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - filter            (optional) a filter function to filter items
+// Returns: a number of data items or error.
+func (c *RedisPersistence) GetCountByFilter(correlationId string, filter func(interface{}) bool) (count int64, err error) {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return 0, err
+	}
+
+	if filter == nil {
+		count, err = c.Client.SCard(c.idsKey()).Result()
+	} else {
+		var items []interface{}
+		_, items, err = c.readByFilter(correlationId, filter)
+		count = int64(len(items))
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	c.Logger.Trace(correlationId, "Counted %d items in %s", count, c.CollectionName)
+	return count, nil
+}
+
+// GetOneRandom method are gets a random item from items that match to a given filter.
+// This method shall be called by a public getOneRandom method from child struct that
+// receives FilterParams and converts them into a filter function.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - filter            (optional) a filter function to filter items
+// Returns: a random item or nil if nothing was found, or error.
+func (c *RedisPersistence) GetOneRandom(correlationId string, filter func(interface{}) bool) (item interface{}, err error) {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return nil, err
+	}
+
+	_, items, err := c.readByFilter(correlationId, filter)
+	if err != nil || len(items) == 0 {
+		return nil, err
+	}
+
+	item = items[rand.Intn(len(items))]
+	c.Logger.Trace(correlationId, "Retrieved random item from %s", c.CollectionName)
+	return item, nil
+}
+
+// Create method are creates a data item. The item must have an id.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - item              an item to be created.
+// Returns: a created item or error.
+func (c *RedisPersistence) Create(correlationId string, item interface{}) (result interface{}, err error) {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return nil, err
+	}
+
+	newItem, err := c.ConvertFromPublic(item)
+	if err != nil {
+		return nil, err
+	}
+
+	id := cconv.StringConverter.ToString(newItem["id"])
+	if id == "" {
+		return nil, cerr.NewBadRequestError(correlationId, "NO_ID", "Item id is not defined")
+	}
+
+	newItem, err = c.save(correlationId, id, func(old map[string]interface{}) (map[string]interface{}, error) {
+		if old != nil {
+			return nil, cerr.NewConflictError(correlationId, "ALREADY_EXISTS", "Item "+id+" already exists").
+				WithDetails("id", id)
+		}
+		return newItem, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c.Logger.Trace(correlationId, "Created in %s with id = %s", c.CollectionName, id)
+	return c.toPublic(newItem)
+}
+
+// DeleteByFilter method are deletes data items that match to a given filter.
+// This method shall be called by a public deleteByFilter method from child struct that
+// receives FilterParams and converts them into a filter function.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - filter            (optional) a filter function to filter items.
+// Returns: error or nil for success.
+func (c *RedisPersistence) DeleteByFilter(correlationId string, filter func(interface{}) bool) error {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return err
+	}
+
+	ids, _, err := c.readByFilter(correlationId, filter)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if _, err = c.remove(correlationId, id); err != nil {
+			return err
+		}
+	}
+
+	c.Logger.Trace(correlationId, "Deleted %d items from %s", len(ids), c.CollectionName)
+	return nil
+}
+
+// EnsureIndex method are declares an index on a field of stored objects.
+// Indexes shall be declared before objects are written, otherwise they shall be rebuilt with RebuildIndexes.
+// Parameters:
+//  - field         a JSON name of the field. Fields of nested objects are separated by dots.
+//  - indexType     a type of the index: SetIndex or SortedIndex.
+func (c *RedisPersistence) EnsureIndex(field string, indexType string) {
+	c.indexes[field] = indexType
+}
+
+func (c *RedisPersistence) setIndexKey(field string, value string) string {
+	return c.keyPrefix() + "idx:set:" + strconv.Itoa(len(field)) + ":" + field + ":" + value
+}
+
+func (c *RedisPersistence) sortedIndexKey(field string) string {
+	return c.keyPrefix() + "idx:sorted:" + field
+}
+
+// writeIndexes queues commands that move the object id from index entries of the old field values
+// to entries of the new ones. A nil item removes the id from all indexes.
+func (c *RedisPersistence) writeIndexes(pipe redis.Pipeliner, id string, old map[string]interface{}, item map[string]interface{}) {
+	for field, indexType := range c.indexes {
+		oldValue := fieldValue(old, field)
+		newValue := fieldValue(item, field)
+
+		if indexType == SortedIndex {
+			if score, ok := indexScore(newValue); ok {
+				pipe.ZAdd(c.sortedIndexKey(field), redis.Z{Score: score, Member: id})
+			} else if old != nil {
+				pipe.ZRem(c.sortedIndexKey(field), id)
+			}
+			continue
+		}
+
+		for _, value := range indexValues(oldValue) {
+			pipe.SRem(c.setIndexKey(field, value), id)
+		}
+		for _, value := range indexValues(newValue) {
+			pipe.SAdd(c.setIndexKey(field, value), id)
+		}
+	}
+}
+
+// deleteIndexes deletes all index keys of the collection.
+func (c *RedisPersistence) deleteIndexes() error {
+	var cursor uint64
+	for {
+		keys, next, err := c.Client.Scan(cursor, c.keyPrefix()+"idx:*", readBatchSize).Result()
+		if err != nil {
+			return err
+		}
+		if len(keys) > 0 {
+			if err = c.Client.Del(keys...).Err(); err != nil {
+				return err
+			}
+		}
+
+		cursor = next
+		if cursor == 0 {
+			return nil
+		}
+	}
+}
+
+// RebuildIndexes method are rebuilds all declared indexes from stored objects.
+// Objects written while indexes are rebuilt may be indexed incorrectly.
+// Parameters:
+//  - correlationId 	(optional) transaction id to trace execution through call chain.
+// Returns: error or nil no errors occured.
+func (c *RedisPersistence) RebuildIndexes(correlationId string) error {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return err
+	}
+
+	if err = c.deleteIndexes(); err != nil {
+		return err
+	}
+
+	ids, items, err := c.readByFilter(correlationId, nil)
+	if err != nil {
+		return err
+	}
+
+	pipe := c.Client.Pipeline()
+	for index, item := range items {
+		fields, err := c.ConvertFromPublic(item)
+		if err != nil {
+			return err
+		}
+		c.writeIndexes(pipe, ids[index], nil, fields)
+	}
+	if _, err = pipe.Exec(); err != nil {
+		return err
+	}
+
+	c.Logger.Debug(correlationId, "Rebuilt indexes for %d items in %s", len(items), c.CollectionName)
+	return nil
+}
+
+// fieldValue gets a value of the field by its dotted path.
+func fieldValue(item map[string]interface{}, field string) interface{} {
+	var value interface{} = item
+	for _, name := range strings.Split(field, ".") {
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = fields[name]
+	}
+	return value
+}
+
+// indexValues converts a field value into set index entries. Every element of arrays is indexed.
+func indexValues(value interface{}) []string {
+	if value == nil {
+		return []string{}
+	}
+
+	if values, ok := value.([]interface{}); ok {
+		result := make([]string, 0, len(values))
+		for _, value := range values {
+			if value != nil {
+				result = append(result, cconv.StringConverter.ToString(value))
+			}
+		}
+		return result
+	}
+	return []string{cconv.StringConverter.ToString(value)}
+}
+
+// indexScore converts a field value into a sorted index score.
+// Times are converted into milliseconds since Unix epoch.
+func indexScore(value interface{}) (score float64, ok bool) {
+	switch v := value.(type) {
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			return float64(t.UnixNano() / int64(time.Millisecond)), true
+		}
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f, true
+		}
+		return 0, false
+	case time.Time:
+		return float64(v.UnixNano() / int64(time.Millisecond)), true
+	case nil:
+		return 0, false
+	}
+
+	f := cconv.DoubleConverter.ToNullableDouble(value)
+	if f == nil {
+		return 0, false
+	}
+	return *f, true
+}
+
+// queryScript filters ids in a temporary sorted set and returns the total number of matching ids
+// and the requested page of them. Filtering starts from the smallest condition, so the whole
+// collection is read only by queries without conditions. A single range on the sort field
+// is read directly from its index.
+// Members of the temporary set are scored 1, so they are ordered by id. When the query is sorted
+// they are intersected with the sort index, and items without a sort value follow sorted items.
+// KEYS are the temporary keys, the ids set, the sorted index of the sort field,
+// the set indexes and the sorted indexes of range conditions.
+// ARGV are numbers of set and range conditions, sort flag, skip, take, descending flag
+// and min and max values of the ranges.
+var queryScript = redis.NewScript(`
+local equals = tonumber(ARGV[1])
+local ranges = tonumber(ARGV[2])
+local sorted = ARGV[3] == "1"
+local skip = tonumber(ARGV[4])
+local take = tonumber(ARGV[5])
+local descending = ARGV[6] == "1"
+
+if equals == 0 and ranges == 1 and sorted and KEYS[5] == KEYS[4] then
+	local min, max = ARGV[7], ARGV[8]
+	local ids = {}
+	if take > 0 and descending then
+		ids = redis.call("ZREVRANGEBYSCORE", KEYS[4], max, min, "LIMIT", skip, take)
+	elseif take > 0 then
+		ids = redis.call("ZRANGEBYSCORE", KEYS[4], min, max, "LIMIT", skip, take)
+	end
+	return {redis.call("ZCOUNT", KEYS[4], min, max), ids}
+end
+
+local seed, size = 0, nil
+for i = 1, equals do
+	local count = redis.call("SCARD", KEYS[4 + i])
+	if not size or count < size then
+		seed, size = i, count
+	end
+end
+for i = 1, ranges do
+	local count = redis.call("ZCOUNT", KEYS[4 + equals + i], ARGV[5 + i * 2], ARGV[6 + i * 2])
+	if not size or count < size then
+		seed, size = equals + i, count
+	end
+end
+
+if seed == 0 then
+	if take == 0 then
+		return {redis.call("SCARD", KEYS[3]), {}}
+	end
+	redis.call("ZUNIONSTORE", KEYS[1], 1, KEYS[3])
+elseif size == 0 then
+	return {0, {}}
+elseif seed <= equals then
+	redis.call("ZUNIONSTORE", KEYS[1], 1, KEYS[4 + seed])
+else
+	local i = seed - equals
+	local ids = redis.call("ZRANGEBYSCORE", KEYS[4 + seed], ARGV[5 + i * 2], ARGV[6 + i * 2])
+	for j = 1, #ids, 1000 do
+		local members = {}
+		for k = j, math.min(j + 999, #ids) do
+			members[#members + 1] = 1
+			members[#members + 1] = ids[k]
+		end
+		redis.call("ZADD", KEYS[1], unpack(members))
+	end
+end
+
+for i = 1, equals do
+	if i ~= seed then
+		redis.call("ZINTERSTORE", KEYS[1], 2, KEYS[1], KEYS[4 + i], "WEIGHTS", 1, 0)
+	end
+end
+for i = 1, ranges do
+	if equals + i ~= seed then
+		redis.call("ZINTERSTORE", KEYS[2], 2, KEYS[4 + equals + i], KEYS[1], "WEIGHTS", 1, 0)
+		local min = ARGV[5 + i * 2]
+		local max = ARGV[6 + i * 2]
+		if min ~= "-inf" then
+			redis.call("ZREMRANGEBYSCORE", KEYS[2], "-inf", "(" .. min)
+		end
+		if max ~= "+inf" then
+			redis.call("ZREMRANGEBYSCORE", KEYS[2], "(" .. max, "+inf")
+		end
+		redis.call("ZINTERSTORE", KEYS[1], 2, KEYS[1], KEYS[2], "WEIGHTS", 1, 0)
+	end
+end
+
+local total = redis.call("ZCARD", KEYS[1])
+local ids = {}
+if take > 0 and not sorted then
+	if descending then
+		ids = redis.call("ZREVRANGE", KEYS[1], skip, skip + take - 1)
+	else
+		ids = redis.call("ZRANGE", KEYS[1], skip, skip + take - 1)
+	end
+elseif take > 0 then
+	local count = redis.call("ZINTERSTORE", KEYS[2], 2, KEYS[1], KEYS[4], "WEIGHTS", 0, 1)
+	if descending then
+		ids = redis.call("ZREVRANGE", KEYS[2], skip, skip + take - 1)
+	else
+		ids = redis.call("ZRANGE", KEYS[2], skip, skip + take - 1)
+	end
+	if skip + take > count then
+		-- Sorted items are scored 0 and the ones without a sort value keep score 1
+		redis.call("ZUNIONSTORE", KEYS[1], 2, KEYS[1], KEYS[2], "WEIGHTS", 1, 0, "AGGREGATE", "MIN")
+		local rest = redis.call("ZRANGEBYSCORE", KEYS[1], 1, 1, "LIMIT", math.max(skip - count, 0), take - #ids)
+		for _, id in ipairs(rest) do
+			ids[#ids + 1] = id
+		end
+	end
+end
+redis.call("DEL", KEYS[1], KEYS[2])
+return {total, ids}
+`)
+
+// runQuery executes the query over indexes and returns the total number of matching items
+// and ids of the requested page.
+func (c *RedisPersistence) runQuery(correlationId string, query *IndexQuery, skip int64, take int64) (total int64, ids []string, err error) {
+	if query == nil {
+		query = NewIndexQuery()
+	}
+
+	tmp := c.keyPrefix() + "tmp:" + cdata.IdGenerator.NextLong()
+	keys := []string{tmp, tmp + ":range", c.idsKey(), c.idsKey()}
+	sorted := "0"
+	if query.Sort != "" {
+		if c.indexes[query.Sort] != SortedIndex {
+			return 0, nil, c.noIndexError(correlationId, query.Sort, SortedIndex)
+		}
+		keys[3] = c.sortedIndexKey(query.Sort)
+		sorted = "1"
+	}
+
+	// Conditions are ordered to make the query script deterministic
+	equalFields := make([]string, 0, len(query.Equals))
+	for field := range query.Equals {
+		equalFields = append(equalFields, field)
+	}
+	sort.Strings(equalFields)
+	for _, field := range equalFields {
+		if c.indexes[field] != SetIndex {
+			return 0, nil, c.noIndexError(correlationId, field, SetIndex)
+		}
+		keys = append(keys, c.setIndexKey(field, cconv.StringConverter.ToString(query.Equals[field])))
+	}
+
+	rangeFields := make([]string, 0, len(query.Ranges))
+	for field := range query.Ranges {
+		rangeFields = append(rangeFields, field)
+	}
+	sort.Strings(rangeFields)
+
+	descending := "0"
+	if query.Descending {
+		descending = "1"
+	}
+	args := []interface{}{len(equalFields), len(rangeFields), sorted, skip, take, descending}
+	for _, field := range rangeFields {
+		if c.indexes[field] != SortedIndex {
+			return 0, nil, c.noIndexError(correlationId, field, SortedIndex)
+		}
+		keys = append(keys, c.sortedIndexKey(field))
+
+		min, err := c.rangeBound(correlationId, field, query.Ranges[field].Min, "-inf")
+		if err != nil {
+			return 0, nil, err
+		}
+		max, err := c.rangeBound(correlationId, field, query.Ranges[field].Max, "+inf")
+		if err != nil {
+			return 0, nil, err
+		}
+		args = append(args, min, max)
+	}
+
+	res, err := queryScript.Run(c.Client, keys, args...).Result()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	values, _ := res.([]interface{})
+	if len(values) < 2 {
+		return 0, nil, cerr.NewInternalError(correlationId, "WRONG_RESULT", "Index query returned unexpected result")
+	}
+	total, _ = values[0].(int64)
+	members, _ := values[1].([]interface{})
+	ids = make([]string, 0, len(members))
+	for _, member := range members {
+		if id, ok := member.(string); ok {
+			ids = append(ids, id)
+		}
+	}
+	return total, ids, nil
+}
+
+// rangeBound converts a range bound into a sorted index score. Nil bounds are open.
+// Bounds that can't be converted are rejected, so the range is never silently widened.
+func (c *RedisPersistence) rangeBound(correlationId string, field string, value interface{}, open string) (string, error) {
+	if value == nil {
+		return open, nil
+	}
+
+	score, ok := indexScore(value)
+	if !ok {
+		err := cerr.NewBadRequestError(
+			correlationId,
+			"WRONG_RANGE",
+			"Range bound of field "+field+" is not a number, boolean or RFC3339 time",
+		).WithDetails("field", field).WithDetails("value", value)
+		return "", err
+	}
+	return strconv.FormatFloat(score, 'f', -1, 64), nil
+}
+
+func (c *RedisPersistence) noIndexError(correlationId string, field string, indexType string) error {
+	return cerr.NewBadRequestError(
+		correlationId,
+		"NO_INDEX",
+		"Field "+field+" has no "+indexType+" index in "+c.CollectionName,
+	).WithDetails("field", field).WithDetails("index_type", indexType)
+}
+
+// GetPageByQuery method are gets a page of data items that match conditions on indexed fields,
+// sorted by an indexed field. Filtering, sorting and paging are performed by Redis.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - query             (optional) conditions on indexed fields and sort field.
+//  - paging            (optional) paging parameters
+// Returns: a data page or error.
+func (c *RedisPersistence) GetPageByQuery(correlationId string, query *IndexQuery,
+	paging *cdata.PagingParams) (page *cdata.DataPage, err error) {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return nil, err
+	}
+
+	if paging == nil {
+		paging = cdata.NewEmptyPagingParams()
+	}
+	skip := paging.GetSkip(0)
+	take := paging.GetTake(c.maxPageSize)
+
+	total, ids, err := c.runQuery(correlationId, query, skip, take)
+	if err != nil {
+		return nil, err
+	}
+
+	_, items, err := c.readItems(correlationId, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Logger.Trace(correlationId, "Retrieved %d items from %s", len(items), c.CollectionName)
+
+	if paging.Total {
+		return cdata.NewDataPage(&total, items), nil
+	}
+	return cdata.NewDataPage(nil, items), nil
+}
+
+// GetCountByQuery method are gets a number of data items that match conditions on indexed fields.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - query             (optional) conditions on indexed fields.
+// Returns: a number of data items or error.
+func (c *RedisPersistence) GetCountByQuery(correlationId string, query *IndexQuery) (count int64, err error) {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return 0, err
+	}
+
+	count, _, err = c.runQuery(correlationId, query, 0, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	c.Logger.Trace(correlationId, "Counted %d items in %s", count, c.CollectionName)
+	return count, nil
+}
@@ -0,0 +1,11 @@
+package queues
+
+import (
+	cqueues "github.com/pip-services3-go/pip-services3-messaging-go/queues"
+)
+
+// PubSubMessageHandler are function called when a message is received on a subscribed channel.
+// Parameters:
+//   - channel   a channel the message was published to.
+//   - envelope  the received message.
+type PubSubMessageHandler func(channel string, envelope *cqueues.MessageEnvelope)
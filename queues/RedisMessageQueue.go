@@ -0,0 +1,731 @@
+package queues
+
+import (
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis"
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+	cref "github.com/pip-services3-go/pip-services3-commons-go/refer"
+	clog "github.com/pip-services3-go/pip-services3-components-go/log"
+	cqueues "github.com/pip-services3-go/pip-services3-messaging-go/queues"
+	redisconn "github.com/pip-services3-go/pip-services3-redis-go/connect"
+)
+
+/*
+RedisMessageQueue are message queue that is implemented based on Redis lists.
+It implements IMessageQueue of pip-services3-messaging-go.
+
+The queue follows reliable queue pattern. Received messages are moved into a processing list
+and locked for a visibility timeout in a single script call. Messages that were not completed, abandoned
+or moved to the dead letter queue before their lock expired are returned into the queue
+by a background timer and delivered again, so messages of crashed receivers are never lost.
+
+The queue is stored under "queue:{<name>}" prefix:
+  - queue:{<name>}               a list of ids of messages waiting for delivery
+  - queue:{<name>}:processing    a list of ids of received messages
+  - queue:{<name>}:locks         a sorted set of ids of received messages scored by their lock expiration time
+  - queue:{<name>}:messages      a hash of message envelopes by their ids
+  - queue:{<name>}:dead          a list of messages moved to the dead letter queue
+  - queue:{<name>}:notify        a list receivers block on until messages become available
+
+Configuration parameters:
+
+  - name:                        name of the message queue
+  - connection(s):
+    - discovery_key:             (optional) a key to retrieve the connection from IDiscovery
+    - host:                      host name or IP address
+    - port:                      port number
+    - uri:                       resource URI or connection string with all parameters in it
+  - credential(s):
+    - store_key:                 key to retrieve parameters from credential store
+    - username:                  user name (currently is not used)
+    - password:                  user password
+  - options:
+    - lock_timeout:              timeout in milliseconds a received message stays invisible to other receivers (default: 30000)
+    - restore_interval:          interval in milliseconds to return messages with expired locks into the queue (default: 1000)
+    - timeout:                   connection timeout in milliseconds (default: 30000)
+    - db_num:                    database number in Redis  (default 0)
+    - cluster:                   enable redis cluster
+
+References:
+
+- *:logger:*:*:1.0           (optional) ILogger components to pass log messages
+- *:discovery:*:*:1.0        (optional) IDiscovery services to resolve connection
+- *:credential-store:*:*:1.0 (optional) Credential stores to resolve credential
+
+Example:
+
+    queue := NewRedisMessageQueue("orders")
+    queue.Configure(cconf.NewConfigParamsFromTuples(
+      "host", "localhost",
+      "port", 6379,
+    ))
+
+    err = queue.Open("123")
+      ...
+
+    err = queue.Send("123", cqueues.NewMessageEnvelope("", "mymessage", []byte("ABC")))
+
+    message, err := queue.Receive("123", 10000*time.Millisecond)
+    if message != nil {
+    	// Processing...
+    	err = queue.Complete(message)
+    }
+*/
+type RedisMessageQueue struct {
+	name         string
+	capabilities *cqueues.MessagingCapabilities
+
+	connectionResolver *redisconn.RedisConnectionResolver
+	logger             *clog.CompositeLogger
+
+	lockTimeout     int64
+	restoreInterval int64
+	cancel          int32
+
+	key          string
+	client       redis.UniversalClient
+	restoreStop  chan struct{}
+	restoreGroup sync.WaitGroup
+}
+
+// listenWaitTimeout is a time Receive blocks in Redis before it checks whether the listening is cancelled.
+const listenWaitTimeout = time.Second
+
+// receivePollInterval is a time Receive waits between checks when less than a second is left to wait.
+const receivePollInterval = 50 * time.Millisecond
+
+// restoreBatchSize is a maximum number of messages with expired locks returned into the queue at once.
+const restoreBatchSize = 100
+
+// NewRedisMessageQueue method are creates a new instance of the message queue.
+// Parameters:
+//   - name  (optional) a queue name.
+func NewRedisMessageQueue(name string) *RedisMessageQueue {
+	c := &RedisMessageQueue{
+		name:               name,
+		capabilities:       cqueues.NewMessagingCapabilities(true, true, true, true, true, true, true, true, true),
+		connectionResolver: redisconn.NewRedisConnectionResolver(),
+		logger:             clog.NewCompositeLogger(),
+		lockTimeout:        30000,
+		restoreInterval:    1000,
+		client:             nil,
+	}
+	return c
+}
+
+// Configure method are configures component by passing configuration parameters.
+// Parameters:
+//   - config    configuration parameters to be set.
+func (c *RedisMessageQueue) Configure(config *cconf.ConfigParams) {
+	c.name = cconf.NameResolver.ResolveWithDefault(config, c.name)
+	c.connectionResolver.Configure(config)
+	c.logger.Configure(config)
+
+	c.lockTimeout = config.GetAsLongWithDefault("options.lock_timeout", c.lockTimeout)
+	c.restoreInterval = config.GetAsLongWithDefault("options.restore_interval", c.restoreInterval)
+}
+
+// SetReferences method are sets references to dependent components.
+// Parameters:
+//   - references 	references to locate the component dependencies.
+func (c *RedisMessageQueue) SetReferences(references cref.IReferences) {
+	c.connectionResolver.SetReferences(references)
+	c.logger.SetReferences(references)
+}
+
+// GetName method are gets the queue name.
+// Returns: the queue name.
+func (c *RedisMessageQueue) GetName() string {
+	return c.name
+}
+
+// GetCapabilities method are gets the queue capabilities.
+// Returns: the queue capabilities object.
+func (c *RedisMessageQueue) GetCapabilities() cqueues.MessagingCapabilities {
+	return *c.capabilities
+}
+
+// IsOpen method are checks if the component is opened.
+// Returns true if the component has been opened and false otherwise.
+func (c *RedisMessageQueue) IsOpen() bool {
+	return c.client != nil
+}
+
+// Open method are opens the component.
+// Parameters:
+// 	- correlationId 	(optional) transaction id to trace execution through call chain.
+// Returns: error or nil no errors occured.
+func (c *RedisMessageQueue) Open(correlationId string) error {
+	if c.name == "" {
+		err := cerr.NewConfigError(correlationId, "NO_NAME", "Queue name is not configured")
+		return err
+	}
+
+	client, err := c.connectionResolver.Connect(correlationId)
+	if err != nil {
+		return err
+	}
+
+	c.key = "queue:{" + c.name + "}"
+	c.client = client
+	c.restoreStop = make(chan struct{})
+	c.restoreGroup.Add(1)
+	go c.restoreMessages(correlationId, client, c.restoreStop)
+
+	c.logger.Debug(correlationId, "Opened queue %s", c.name)
+	return nil
+}
+
+// Close method are closes component and frees used resources.
+// Parameters:
+//  - correlationId 	(optional) transaction id to trace execution through call chain.
+// Retruns: error or nil no errors occured.
+func (c *RedisMessageQueue) Close(correlationId string) error {
+	if c.client != nil {
+		c.EndListen(correlationId)
+		close(c.restoreStop)
+		c.restoreGroup.Wait()
+
+		err := c.client.Close()
+		c.client = nil
+		if err != nil {
+			return err
+		}
+		c.logger.Debug(correlationId, "Closed queue %s", c.name)
+	}
+	return nil
+}
+
+func (c *RedisMessageQueue) checkOpened(correlationId string) (state bool, err error) {
+	if !c.IsOpen() {
+		err = cerr.NewInvalidStateError(correlationId, "NOT_OPENED", "The queue is not opened")
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Clear method are clears component state by removing all messages from the queue.
+// Parameters:
+//  - correlationId 	(optional) transaction id to trace execution through call chain.
+// Returns: error or nil no errors occured.
+func (c *RedisMessageQueue) Clear(correlationId string) error {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return err
+	}
+
+	return c.client.Del(c.key, c.key+":processing", c.key+":locks", c.key+":messages", c.key+":dead", c.key+":notify").Err()
+}
+
+// MessageCount method are reads the current number of messages in the queue to be delivered.
+// Returns: number of messages or error.
+func (c *RedisMessageQueue) MessageCount() (count int64, err error) {
+	state, err := c.checkOpened("")
+	if !state {
+		return 0, err
+	}
+
+	return c.client.LLen(c.key).Result()
+}
+
+// Send method are sends a message into the queue.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - envelope          a message envelop to be sent.
+// Returns: error or nil for success.
+func (c *RedisMessageQueue) Send(correlationId string, envelope *cqueues.MessageEnvelope) error {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return err
+	}
+
+	if envelope.MessageId == "" {
+		envelope.MessageId = cdata.IdGenerator.NextLong()
+	}
+	if envelope.CorrelationId == "" {
+		envelope.CorrelationId = correlationId
+	}
+	envelope.SentTime = time.Now().UTC()
+
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+
+	pipe := c.client.TxPipeline()
+	pipe.HSet(c.key+":messages", envelope.MessageId, data)
+	pipe.LPush(c.key, envelope.MessageId)
+	c.notifyReceivers(pipe)
+	_, err = pipe.Exec()
+	if err != nil {
+		return err
+	}
+
+	c.logger.Debug(envelope.CorrelationId, "Sent message %s via %s", envelope.String(), c.name)
+	return nil
+}
+
+// SendAsObject method are sends an object into the queue.
+// Before sending the object is converted into JSON string and wrapped in a MessageEnvelope.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - messageType       a message type.
+//  - message           an object value to be sent.
+// Returns: error or nil for success.
+func (c *RedisMessageQueue) SendAsObject(correlationId string, messageType string, message interface{}) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	envelope := cqueues.NewMessageEnvelope(correlationId, messageType, data)
+	return c.Send(correlationId, envelope)
+}
+
+// Peek method are peeks a single incoming message from the queue without removing it.
+// If there are no messages available in the queue it returns nil.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+// Returns: a peeked message or error.
+func (c *RedisMessageQueue) Peek(correlationId string) (result *cqueues.MessageEnvelope, err error) {
+	messages, err := c.PeekBatch(correlationId, 1)
+	if err != nil || len(messages) == 0 {
+		return nil, err
+	}
+	return messages[0], nil
+}
+
+// PeekBatch method are peeks multiple incoming messages from the queue without removing them.
+// If there are no messages available in the queue it returns an empty list.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - messageCount      a maximum number of messages to peek.
+// Returns: a list of peeked messages or error.
+func (c *RedisMessageQueue) PeekBatch(correlationId string, messageCount int64) (result []*cqueues.MessageEnvelope, err error) {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return nil, err
+	}
+
+	result = []*cqueues.MessageEnvelope{}
+	if messageCount <= 0 {
+		return result, nil
+	}
+
+	// Messages are delivered from the list tail
+	ids, err := c.client.LRange(c.key, -messageCount, -1).Result()
+	if err != nil || len(ids) == 0 {
+		return result, err
+	}
+
+	values, err := c.client.HMGet(c.key+":messages", ids...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(values) - 1; i >= 0; i-- {
+		data, ok := values[i].(string)
+		if !ok {
+			continue
+		}
+		envelope := cqueues.NewEmptyMessageEnvelope()
+		if err = json.Unmarshal([]byte(data), envelope); err != nil {
+			return nil, err
+		}
+		result = append(result, envelope)
+	}
+
+	c.logger.Trace(correlationId, "Peeked %d messages on %s", len(result), c.name)
+	return result, nil
+}
+
+// Script time is taken from the Redis server clock, so receivers do not depend on their local clocks.
+
+// restoreScript returns a batch of messages with expired locks back into the queue.
+// It returns the number of restored messages.
+var restoreScript = redis.NewScript(`
+redis.replicate_commands()
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local ids = redis.call("ZRANGEBYSCORE", KEYS[3], "-inf", now, "LIMIT", 0, tonumber(ARGV[1]))
+local restored = 0
+for _, id in ipairs(ids) do
+	redis.call("ZREM", KEYS[3], id)
+	if redis.call("LREM", KEYS[2], 1, id) > 0 then
+		redis.call("RPUSH", KEYS[1], id)
+		restored = restored + 1
+	end
+end
+if restored > 0 then
+	redis.call("LPUSH", KEYS[4], 1)
+	redis.call("LTRIM", KEYS[4], 0, 0)
+end
+return restored
+`)
+
+// takeScript moves the next message into the processing list and locks it,
+// so messages of receivers that crash in between are never left unlocked.
+// It returns the message id or nil when the queue is empty.
+var takeScript = redis.NewScript(`
+redis.replicate_commands()
+local id = redis.call("RPOPLPUSH", KEYS[1], KEYS[2])
+if not id then
+	return nil
+end
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+redis.call("ZADD", KEYS[3], now + tonumber(ARGV[1]), id)
+return id
+`)
+
+// lockScript locks a received message until the lock timeout expires.
+// With "XX" flag it only renews locks of messages that are still locked.
+var lockScript = redis.NewScript(`
+redis.replicate_commands()
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+if ARGV[3] == "XX" then
+	return redis.call("ZADD", KEYS[1], "XX", "CH", now + tonumber(ARGV[2]), ARGV[1])
+end
+return redis.call("ZADD", KEYS[1], now + tonumber(ARGV[2]), ARGV[1])
+`)
+
+// completeScript removes a received message from the queue.
+var completeScript = redis.NewScript(`
+redis.call("LREM", KEYS[1], 1, ARGV[1])
+redis.call("ZREM", KEYS[2], ARGV[1])
+return redis.call("HDEL", KEYS[3], ARGV[1])
+`)
+
+// abandonScript returns a received message to the head of the queue.
+var abandonScript = redis.NewScript(`
+if redis.call("LREM", KEYS[2], 1, ARGV[1]) == 0 then
+	return 0
+end
+redis.call("ZREM", KEYS[3], ARGV[1])
+redis.call("RPUSH", KEYS[1], ARGV[1])
+redis.call("LPUSH", KEYS[4], 1)
+redis.call("LTRIM", KEYS[4], 0, 0)
+return 1
+`)
+
+// deadLetterScript moves a received message into the dead letter list.
+var deadLetterScript = redis.NewScript(`
+if redis.call("LREM", KEYS[1], 1, ARGV[1]) == 0 then
+	return 0
+end
+redis.call("ZREM", KEYS[2], ARGV[1])
+local message = redis.call("HGET", KEYS[3], ARGV[1])
+if message then
+	redis.call("HDEL", KEYS[3], ARGV[1])
+	redis.call("LPUSH", KEYS[4], message)
+end
+return 1
+`)
+
+// Receive method are receives an incoming message and removes it from the queue.
+// The message stays in the processing list and is invisible to other receivers
+// until it is completed, abandoned or its lock expires.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - waitTimeout       a timeout to wait for a message to come.
+// Returns: a received message or nil if no messages came before the timeout, or error.
+func (c *RedisMessageQueue) Receive(correlationId string, waitTimeout time.Duration) (result *cqueues.MessageEnvelope, err error) {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return nil, err
+	}
+
+	expireTime := time.Now().Add(waitTimeout)
+
+	for {
+		id, err := takeScript.Run(c.client, []string{c.key, c.key + ":processing", c.key + ":locks"}, c.lockTimeout).String()
+		if err != nil && err != redis.Nil {
+			return nil, err
+		}
+
+		if id != "" {
+			result, err = c.readMessage(correlationId, id)
+			if result != nil || err != nil {
+				return result, err
+			}
+			continue
+		}
+
+		// Block in Redis until a message is sent, abandoned or restored
+		wait := time.Until(expireTime)
+		if wait <= 0 {
+			return nil, nil
+		}
+		if wait > listenWaitTimeout {
+			wait = listenWaitTimeout
+		}
+		if wait < time.Second {
+			// Redis blocks only for whole seconds, so short waits are polled
+			// to not block longer than the wait timeout
+			if wait > receivePollInterval {
+				wait = receivePollInterval
+			}
+			time.Sleep(wait)
+			continue
+		}
+		err = c.client.BRPop(wait.Truncate(time.Second), c.key+":notify").Err()
+		if err != nil && err != redis.Nil {
+			return nil, err
+		}
+	}
+}
+
+// readMessage reads the envelope of the received message.
+// Returns nil when the message was removed by Clear in between.
+func (c *RedisMessageQueue) readMessage(correlationId string, id string) (result *cqueues.MessageEnvelope, err error) {
+	data, err := c.client.HGet(c.key+":messages", id).Bytes()
+	if err == redis.Nil {
+		err = completeScript.Run(c.client, []string{c.key + ":processing", c.key + ":locks", c.key + ":messages"}, id).Err()
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result = cqueues.NewEmptyMessageEnvelope()
+	if err = json.Unmarshal(data, result); err != nil {
+		return nil, err
+	}
+	result.SetReference(id)
+
+	c.logger.Debug(result.CorrelationId, "Received message %s via %s", result.String(), c.name)
+	return result, nil
+}
+
+// notifyReceivers wakes up a receiver blocked in Receive.
+// A single pending notification is kept, since every receiver checks the queue after it wakes up.
+func (c *RedisMessageQueue) notifyReceivers(pipe redis.Pipeliner) {
+	pipe.LPush(c.key+":notify", 1)
+	pipe.LTrim(c.key+":notify", 0, 0)
+}
+
+// restoreMessages periodically returns messages with expired locks into the queue
+// until the queue is closed.
+func (c *RedisMessageQueue) restoreMessages(correlationId string, client redis.UniversalClient, stop chan struct{}) {
+	defer c.restoreGroup.Done()
+
+	interval := time.Duration(c.restoreInterval) * time.Millisecond
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	keys := []string{c.key, c.key + ":processing", c.key + ":locks", c.key + ":notify"}
+	for {
+		restored, err := restoreScript.Run(client, keys, restoreBatchSize).Int64()
+		if err != nil {
+			c.logger.Error(correlationId, err, "Failed to restore expired messages at %s", c.name)
+		} else if restored > 0 {
+			c.logger.Debug(correlationId, "Restored %d expired messages at %s", restored, c.name)
+		}
+
+		// Full batches are followed by the next ones without waiting
+		if restored < restoreBatchSize {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		} else {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+		}
+	}
+}
+
+// RenewLock method are renews a lock on a message that makes it invisible from other receivers in the queue.
+// This method is usually used to extend the message processing time.
+// Parameters:
+//  - message       a message to extend its lock.
+//  - lockTimeout   a locking timeout.
+// Returns: error or nil for success.
+func (c *RedisMessageQueue) RenewLock(message *cqueues.MessageEnvelope, lockTimeout time.Duration) error {
+	state, err := c.checkOpened(message.CorrelationId)
+	if !state {
+		return err
+	}
+
+	id, ok := message.GetReference().(string)
+	if !ok {
+		return nil
+	}
+
+	timeout := int64(lockTimeout / time.Millisecond)
+	err = lockScript.Run(c.client, []string{c.key + ":locks"}, id, timeout, "XX").Err()
+	if err != nil {
+		return err
+	}
+
+	c.logger.Trace(message.CorrelationId, "Renewed lock for message %s at %s", message.MessageId, c.name)
+	return nil
+}
+
+// Complete method are permanently removes a message from the queue.
+// This method is usually used to remove the message after successful processing.
+// Parameters:
+//  - message   a message to remove.
+// Returns: error or nil for success.
+func (c *RedisMessageQueue) Complete(message *cqueues.MessageEnvelope) error {
+	state, err := c.checkOpened(message.CorrelationId)
+	if !state {
+		return err
+	}
+
+	id, ok := message.GetReference().(string)
+	if !ok {
+		return nil
+	}
+
+	err = completeScript.Run(c.client, []string{c.key + ":processing", c.key + ":locks", c.key + ":messages"}, id).Err()
+	if err != nil {
+		return err
+	}
+	message.SetReference(nil)
+
+	c.logger.Trace(message.CorrelationId, "Completed message %s at %s", message.MessageId, c.name)
+	return nil
+}
+
+// Abandon method are returns message into the queue and makes it available for all subscribers to receive it again.
+// This method is usually used to return a message which could not be processed at the moment
+// to repeat the attempt. Messages that cause unrecoverable errors shall be removed permanently
+// or/and send to dead letter queue.
+// Parameters:
+//  - message   a message to return.
+// Returns: error or nil for success.
+func (c *RedisMessageQueue) Abandon(message *cqueues.MessageEnvelope) error {
+	state, err := c.checkOpened(message.CorrelationId)
+	if !state {
+		return err
+	}
+
+	id, ok := message.GetReference().(string)
+	if !ok {
+		return nil
+	}
+
+	err = abandonScript.Run(c.client, []string{c.key, c.key + ":processing", c.key + ":locks", c.key + ":notify"}, id).Err()
+	if err != nil {
+		return err
+	}
+	message.SetReference(nil)
+
+	c.logger.Trace(message.CorrelationId, "Abandoned message %s at %s", message.MessageId, c.name)
+	return nil
+}
+
+// MoveToDeadLetter method are permanently removes a message from the queue and sends it to dead letter queue.
+// Parameters:
+//  - message   a message to be removed.
+// Returns: error or nil for success.
+func (c *RedisMessageQueue) MoveToDeadLetter(message *cqueues.MessageEnvelope) error {
+	state, err := c.checkOpened(message.CorrelationId)
+	if !state {
+		return err
+	}
+
+	id, ok := message.GetReference().(string)
+	if !ok {
+		return nil
+	}
+
+	keys := []string{c.key + ":processing", c.key + ":locks", c.key + ":messages", c.key + ":dead"}
+	err = deadLetterScript.Run(c.client, keys, id).Err()
+	if err != nil {
+		return err
+	}
+	message.SetReference(nil)
+
+	c.logger.Trace(message.CorrelationId, "Moved to dead message %s at %s", message.MessageId, c.name)
+	return nil
+}
+
+// Listen method are listens for incoming messages and blocks the current thread until queue is closed.
+// Messages that failed to be processed are delivered again after their locks expire.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - receiver          a receiver to receive incoming messages.
+// Returns: error or nil for success.
+func (c *RedisMessageQueue) Listen(correlationId string, receiver cqueues.IMessageReceiver) error {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return err
+	}
+
+	return listenMessages(correlationId, c, receiver, &c.cancel, c.logger)
+}
+
+// listenMessages receives messages from the queue and passes them to the receiver
+// until the listening is cancelled or the queue is closed.
+// Messages that failed to be processed are delivered again after their locks expire.
+func listenMessages(correlationId string, queue cqueues.IMessageQueue, receiver cqueues.IMessageReceiver,
+	cancel *int32, logger *clog.CompositeLogger) error {
+	logger.Trace(correlationId, "Started listening messages at %s", queue.GetName())
+	atomic.StoreInt32(cancel, 0)
+
+	for atomic.LoadInt32(cancel) == 0 && queue.IsOpen() {
+		message, err := queue.Receive(correlationId, listenWaitTimeout)
+		if err != nil {
+			logger.Error(correlationId, err, "Failed to receive the message")
+			time.Sleep(listenWaitTimeout)
+			continue
+		}
+
+		if message != nil && atomic.LoadInt32(cancel) == 0 {
+			if err = receiver.ReceiveMessage(message, queue); err != nil {
+				logger.Error(correlationId, err, "Failed to process the message")
+			}
+		}
+	}
+
+	logger.Trace(correlationId, "Stopped listening messages at %s", queue.GetName())
+	return nil
+}
+
+// BeginListen method are listens for incoming messages without blocking the current thread.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - receiver          a receiver to receive incoming messages.
+func (c *RedisMessageQueue) BeginListen(correlationId string, receiver cqueues.IMessageReceiver) {
+	go func() {
+		err := c.Listen(correlationId, receiver)
+		if err != nil {
+			c.logger.Error(correlationId, err, "Failed to listen messages at %s", c.name)
+		}
+	}()
+}
+
+// EndListen method are ends listening for incoming messages.
+// When this method is call Listen unblocks the thread and execution continues.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+func (c *RedisMessageQueue) EndListen(correlationId string) {
+	atomic.StoreInt32(&c.cancel, 1)
+}
+
+// ReadDeadLetterCount method are reads the number of messages moved to the dead letter queue.
+// Returns: number of messages or error.
+func (c *RedisMessageQueue) ReadDeadLetterCount() (count int64, err error) {
+	state, err := c.checkOpened("")
+	if !state {
+		return 0, err
+	}
+
+	return c.client.LLen(c.key + ":dead").Result()
+}
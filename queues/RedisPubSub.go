@@ -0,0 +1,322 @@
+package queues
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+	cref "github.com/pip-services3-go/pip-services3-commons-go/refer"
+	clog "github.com/pip-services3-go/pip-services3-components-go/log"
+	cqueues "github.com/pip-services3-go/pip-services3-messaging-go/queues"
+	redisconn "github.com/pip-services3-go/pip-services3-redis-go/connect"
+)
+
+/*
+RedisPubSub are message bus for fire-and-forget broadcast messages that is implemented based on Redis pub/sub.
+
+Messages are published as JSON encoded MessageEnvelope, so subscribers receive
+the correlation id and the message type together with the message.
+Messages published while a subscriber is disconnected are not delivered to it.
+All subscriptions share a single connection that is restored together with
+the subscriptions after it is lost.
+
+Configuration parameters:
+
+  - connection(s):
+    - discovery_key:             (optional) a key to retrieve the connection from IDiscovery
+    - host:                      host name or IP address
+    - port:                      port number
+    - uri:                       resource URI or connection string with all parameters in it
+  - credential(s):
+    - store_key:                 key to retrieve parameters from credential store
+    - username:                  user name (currently is not used)
+    - password:                  user password
+  - options:
+    - timeout:                   connection timeout in milliseconds (default: 30000)
+    - db_num:                    database number in Redis  (default 0)
+    - cluster:                   enable redis cluster
+
+References:
+
+- *:logger:*:*:1.0           (optional) ILogger components to pass log messages
+- *:discovery:*:*:1.0        (optional) IDiscovery services to resolve connection
+- *:credential-store:*:*:1.0 (optional) Credential stores to resolve credential
+
+Example:
+
+    bus := NewRedisPubSub()
+    bus.Configure(cconf.NewConfigParamsFromTuples(
+      "host", "localhost",
+      "port", 6379,
+    ))
+
+    err = bus.Open("123")
+      ...
+
+    err = bus.PSubscribe("123", "cache:*", func(channel string, envelope *cqueues.MessageEnvelope) {
+    	var key string
+    	json.Unmarshal(envelope.Message, &key)
+    	// Invalidating the key...
+    })
+
+    err = bus.PublishAsObject("123", "cache:users", "invalidate", "user_1")
+*/
+type RedisPubSub struct {
+	connectionResolver *redisconn.RedisConnectionResolver
+	logger             *clog.CompositeLogger
+
+	channels   map[string][]PubSubMessageHandler
+	patterns   map[string][]PubSubMessageHandler
+	handlersMx sync.RWMutex
+
+	pubsub *redis.PubSub
+	client redis.UniversalClient
+}
+
+// NewRedisPubSub method are creates a new instance of the message bus.
+func NewRedisPubSub() *RedisPubSub {
+	c := &RedisPubSub{
+		connectionResolver: redisconn.NewRedisConnectionResolver(),
+		logger:             clog.NewCompositeLogger(),
+		channels:           map[string][]PubSubMessageHandler{},
+		patterns:           map[string][]PubSubMessageHandler{},
+		client:             nil,
+	}
+	return c
+}
+
+// Configure method are configures component by passing configuration parameters.
+// Parameters:
+//   - config    configuration parameters to be set.
+func (c *RedisPubSub) Configure(config *cconf.ConfigParams) {
+	c.connectionResolver.Configure(config)
+	c.logger.Configure(config)
+}
+
+// SetReferences method are sets references to dependent components.
+// Parameters:
+//   - references 	references to locate the component dependencies.
+func (c *RedisPubSub) SetReferences(references cref.IReferences) {
+	c.connectionResolver.SetReferences(references)
+	c.logger.SetReferences(references)
+}
+
+// IsOpen method are checks if the component is opened.
+// Returns true if the component has been opened and false otherwise.
+func (c *RedisPubSub) IsOpen() bool {
+	return c.client != nil
+}
+
+// Open method are opens the component and restores subscriptions made before it was opened.
+// Parameters:
+// 	- correlationId 	(optional) transaction id to trace execution through call chain.
+// Returns: error or nil no errors occured.
+func (c *RedisPubSub) Open(correlationId string) error {
+	client, err := c.connectionResolver.Connect(correlationId)
+	if err != nil {
+		return err
+	}
+
+	c.handlersMx.RLock()
+	channels := make([]string, 0, len(c.channels))
+	for channel := range c.channels {
+		channels = append(channels, channel)
+	}
+	patterns := make([]string, 0, len(c.patterns))
+	for pattern := range c.patterns {
+		patterns = append(patterns, pattern)
+	}
+	c.handlersMx.RUnlock()
+
+	// The subscriber reconnects and resubscribes to its channels when the connection is lost
+	pubsub := client.Subscribe(channels...)
+	if len(patterns) > 0 {
+		if err = pubsub.PSubscribe(patterns...); err != nil {
+			pubsub.Close()
+			client.Close()
+			return err
+		}
+	}
+
+	c.client = client
+	c.pubsub = pubsub
+	go c.dispatch(correlationId, pubsub.Channel())
+	return nil
+}
+
+// Close method are closes component and frees used resources.
+// Subscriptions are kept and restored when the component is opened again.
+// Parameters:
+//  - correlationId 	(optional) transaction id to trace execution through call chain.
+// Retruns: error or nil no errors occured.
+func (c *RedisPubSub) Close(correlationId string) error {
+	if c.client != nil {
+		c.pubsub.Close()
+		c.pubsub = nil
+
+		err := c.client.Close()
+		c.client = nil
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *RedisPubSub) checkOpened(correlationId string) (state bool, err error) {
+	if !c.IsOpen() {
+		err = cerr.NewInvalidStateError(correlationId, "NOT_OPENED", "Connection is not opened")
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Publish method are publishes a message to the channel.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - channel           a channel to publish the message to.
+//  - envelope          a message envelop to be published.
+// Returns: error or nil for success.
+func (c *RedisPubSub) Publish(correlationId string, channel string, envelope *cqueues.MessageEnvelope) error {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return err
+	}
+
+	if envelope.MessageId == "" {
+		envelope.MessageId = cdata.IdGenerator.NextLong()
+	}
+	if envelope.CorrelationId == "" {
+		envelope.CorrelationId = correlationId
+	}
+	envelope.SentTime = time.Now().UTC()
+
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+
+	if err = c.client.Publish(channel, data).Err(); err != nil {
+		return err
+	}
+
+	c.logger.Trace(envelope.CorrelationId, "Published message %s to %s", envelope.String(), channel)
+	return nil
+}
+
+// PublishAsObject method are publishes an object to the channel.
+// Before publishing the object is converted into JSON string and wrapped in a MessageEnvelope.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - channel           a channel to publish the message to.
+//  - messageType       a message type.
+//  - message           an object value to be published.
+// Returns: error or nil for success.
+func (c *RedisPubSub) PublishAsObject(correlationId string, channel string, messageType string, message interface{}) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	envelope := cqueues.NewMessageEnvelope(correlationId, messageType, data)
+	return c.Publish(correlationId, channel, envelope)
+}
+
+// Subscribe method are subscribes the handler to messages published to the channel.
+// Subscriptions can be made before the component is opened.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - channel           a channel to subscribe to.
+//  - handler           a function called for every received message.
+// Returns: error or nil for success.
+func (c *RedisPubSub) Subscribe(correlationId string, channel string, handler PubSubMessageHandler) error {
+	c.handlersMx.Lock()
+	_, subscribed := c.channels[channel]
+	c.channels[channel] = append(c.channels[channel], handler)
+	c.handlersMx.Unlock()
+
+	if subscribed || c.pubsub == nil {
+		return nil
+	}
+	return c.pubsub.Subscribe(channel)
+}
+
+// PSubscribe method are subscribes the handler to messages published to channels that match the pattern.
+// Subscriptions can be made before the component is opened.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - pattern           a glob-style pattern of channel names, for instance "cache:*".
+//  - handler           a function called for every received message.
+// Returns: error or nil for success.
+func (c *RedisPubSub) PSubscribe(correlationId string, pattern string, handler PubSubMessageHandler) error {
+	c.handlersMx.Lock()
+	_, subscribed := c.patterns[pattern]
+	c.patterns[pattern] = append(c.patterns[pattern], handler)
+	c.handlersMx.Unlock()
+
+	if subscribed || c.pubsub == nil {
+		return nil
+	}
+	return c.pubsub.PSubscribe(pattern)
+}
+
+// Unsubscribe method are removes all handlers subscribed to the channel.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - channel           a channel to unsubscribe from.
+// Returns: error or nil for success.
+func (c *RedisPubSub) Unsubscribe(correlationId string, channel string) error {
+	c.handlersMx.Lock()
+	delete(c.channels, channel)
+	c.handlersMx.Unlock()
+
+	if c.pubsub == nil {
+		return nil
+	}
+	return c.pubsub.Unsubscribe(channel)
+}
+
+// PUnsubscribe method are removes all handlers subscribed to the pattern.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - pattern           a pattern to unsubscribe from.
+// Returns: error or nil for success.
+func (c *RedisPubSub) PUnsubscribe(correlationId string, pattern string) error {
+	c.handlersMx.Lock()
+	delete(c.patterns, pattern)
+	c.handlersMx.Unlock()
+
+	if c.pubsub == nil {
+		return nil
+	}
+	return c.pubsub.PUnsubscribe(pattern)
+}
+
+// dispatch passes received messages to the handlers of their channels and patterns
+// until the subscriber is closed.
+func (c *RedisPubSub) dispatch(correlationId string, messages <-chan *redis.Message) {
+	for message := range messages {
+		envelope := cqueues.NewEmptyMessageEnvelope()
+		if err := json.Unmarshal([]byte(message.Payload), envelope); err != nil {
+			c.logger.Warn(correlationId, "Received invalid message on %s: %s", message.Channel, err.Error())
+			continue
+		}
+
+		c.handlersMx.RLock()
+		var handlers []PubSubMessageHandler
+		if message.Pattern != "" {
+			handlers = c.patterns[message.Pattern]
+		} else {
+			handlers = c.channels[message.Channel]
+		}
+		c.handlersMx.RUnlock()
+
+		for _, handler := range handlers {
+			handler(message.Channel, envelope)
+		}
+	}
+}
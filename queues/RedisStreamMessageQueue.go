@@ -0,0 +1,711 @@
+package queues
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis"
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cconv "github.com/pip-services3-go/pip-services3-commons-go/convert"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+	cref "github.com/pip-services3-go/pip-services3-commons-go/refer"
+	clog "github.com/pip-services3-go/pip-services3-components-go/log"
+	cqueues "github.com/pip-services3-go/pip-services3-messaging-go/queues"
+	redisconn "github.com/pip-services3-go/pip-services3-redis-go/connect"
+)
+
+/*
+RedisStreamMessageQueue are message queue that is implemented based on Redis streams.
+It implements IMessageQueue of pip-services3-messaging-go.
+
+Messages are appended to "stream:{<name>}" stream and read through a consumer group,
+so every group receives all the messages, while consumers of the same group share them.
+Messages stay in the stream after they are completed and can be replayed by new groups
+until the stream is trimmed to its maximum length.
+
+Received messages stay pending in the group until they are completed. Messages pending
+longer than the claim timeout, for instance because their consumer died, are claimed
+by other consumers of the group and delivered again. Messages delivered more times
+than allowed are moved to "stream:{<name>}:dead" stream.
+
+Configuration parameters:
+
+  - name:                        name of the message queue
+  - connection(s):
+    - discovery_key:             (optional) a key to retrieve the connection from IDiscovery
+    - host:                      host name or IP address
+    - port:                      port number
+    - uri:                       resource URI or connection string with all parameters in it
+  - credential(s):
+    - store_key:                 key to retrieve parameters from credential store
+    - username:                  user name (currently is not used)
+    - password:                  user password
+  - options:
+    - group:                     name of the consumer group (default: "default")
+    - consumer:                  unique name of this consumer in the group (default: generated name)
+    - start_id:                  id in the stream to start reading from when the group is created, "$" to read only new messages (default: "0")
+    - max_length:                approximate maximum number of messages kept in the stream, 0 to keep all (default: 0)
+    - claim_timeout:             timeout in milliseconds after which pending messages are claimed by other consumers (default: 30000)
+    - max_deliveries:            number of deliveries after which a message is moved to the dead letter stream, 0 to retry forever (default: 0)
+    - timeout:                   connection timeout in milliseconds (default: 30000)
+    - db_num:                    database number in Redis  (default 0)
+    - cluster:                   enable redis cluster
+
+References:
+
+- *:logger:*:*:1.0           (optional) ILogger components to pass log messages
+- *:discovery:*:*:1.0        (optional) IDiscovery services to resolve connection
+- *:credential-store:*:*:1.0 (optional) Credential stores to resolve credential
+
+Example:
+
+    queue := NewRedisStreamMessageQueue("events")
+    queue.Configure(cconf.NewConfigParamsFromTuples(
+      "host", "localhost",
+      "port", 6379,
+      "options.group", "billing",
+      "options.max_length", 100000,
+      "options.max_deliveries", 5,
+    ))
+
+    err = queue.Open("123")
+      ...
+
+    err = queue.Send("123", cqueues.NewMessageEnvelope("", "mymessage", []byte("ABC")))
+
+    message, err := queue.Receive("123", 10000*time.Millisecond)
+    if message != nil {
+    	// Processing...
+    	err = queue.Complete(message)
+    }
+*/
+type RedisStreamMessageQueue struct {
+	name         string
+	capabilities *cqueues.MessagingCapabilities
+
+	connectionResolver *redisconn.RedisConnectionResolver
+	logger             *clog.CompositeLogger
+
+	group         string
+	consumer      string
+	startId       string
+	maxLength     int64
+	claimTimeout  int64
+	maxDeliveries int64
+	cancel        int32
+
+	key    string
+	client redis.UniversalClient
+}
+
+// streamClaimBatch is a maximum number of pending messages checked for claiming at once.
+const streamClaimBatch = 100
+
+// NewRedisStreamMessageQueue method are creates a new instance of the message queue.
+// Parameters:
+//   - name  (optional) a queue name.
+func NewRedisStreamMessageQueue(name string) *RedisStreamMessageQueue {
+	host, _ := os.Hostname()
+	c := &RedisStreamMessageQueue{
+		name:               name,
+		capabilities:       cqueues.NewMessagingCapabilities(true, true, true, true, true, true, true, true, true),
+		connectionResolver: redisconn.NewRedisConnectionResolver(),
+		logger:             clog.NewCompositeLogger(),
+		group:              "default",
+		consumer:           host + "-" + cdata.IdGenerator.NextShort(),
+		startId:            "0",
+		claimTimeout:       30000,
+		client:             nil,
+	}
+	return c
+}
+
+// Configure method are configures component by passing configuration parameters.
+// Parameters:
+//   - config    configuration parameters to be set.
+func (c *RedisStreamMessageQueue) Configure(config *cconf.ConfigParams) {
+	c.name = cconf.NameResolver.ResolveWithDefault(config, c.name)
+	c.connectionResolver.Configure(config)
+	c.logger.Configure(config)
+
+	c.group = config.GetAsStringWithDefault("options.group", c.group)
+	c.consumer = config.GetAsStringWithDefault("options.consumer", c.consumer)
+	c.startId = config.GetAsStringWithDefault("options.start_id", c.startId)
+	c.maxLength = config.GetAsLongWithDefault("options.max_length", c.maxLength)
+	c.claimTimeout = config.GetAsLongWithDefault("options.claim_timeout", c.claimTimeout)
+	c.maxDeliveries = config.GetAsLongWithDefault("options.max_deliveries", c.maxDeliveries)
+}
+
+// SetReferences method are sets references to dependent components.
+// Parameters:
+//   - references 	references to locate the component dependencies.
+func (c *RedisStreamMessageQueue) SetReferences(references cref.IReferences) {
+	c.connectionResolver.SetReferences(references)
+	c.logger.SetReferences(references)
+}
+
+// GetName method are gets the queue name.
+// Returns: the queue name.
+func (c *RedisStreamMessageQueue) GetName() string {
+	return c.name
+}
+
+// GetCapabilities method are gets the queue capabilities.
+// Returns: the queue capabilities object.
+func (c *RedisStreamMessageQueue) GetCapabilities() cqueues.MessagingCapabilities {
+	return *c.capabilities
+}
+
+// IsOpen method are checks if the component is opened.
+// Returns true if the component has been opened and false otherwise.
+func (c *RedisStreamMessageQueue) IsOpen() bool {
+	return c.client != nil
+}
+
+// Open method are opens the component and creates the consumer group when it does not exist.
+// Parameters:
+// 	- correlationId 	(optional) transaction id to trace execution through call chain.
+// Returns: error or nil no errors occured.
+func (c *RedisStreamMessageQueue) Open(correlationId string) error {
+	if c.name == "" {
+		err := cerr.NewConfigError(correlationId, "NO_NAME", "Queue name is not configured")
+		return err
+	}
+
+	client, err := c.connectionResolver.Connect(correlationId)
+	if err != nil {
+		return err
+	}
+
+	c.key = "stream:{" + c.name + "}"
+	if err = c.createGroup(client); err != nil {
+		client.Close()
+		return err
+	}
+
+	c.client = client
+	c.logger.Debug(correlationId, "Opened queue %s for group %s", c.name, c.group)
+	return nil
+}
+
+// createGroup creates the consumer group together with the stream, unless it already exists.
+func (c *RedisStreamMessageQueue) createGroup(client redis.UniversalClient) error {
+	err := client.XGroupCreateMkStream(c.key, c.group, c.startId).Err()
+	if err != nil && strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return nil
+	}
+	return err
+}
+
+// Close method are closes component and frees used resources.
+// Parameters:
+//  - correlationId 	(optional) transaction id to trace execution through call chain.
+// Retruns: error or nil no errors occured.
+func (c *RedisStreamMessageQueue) Close(correlationId string) error {
+	if c.client != nil {
+		c.EndListen(correlationId)
+
+		err := c.client.Close()
+		c.client = nil
+		if err != nil {
+			return err
+		}
+		c.logger.Debug(correlationId, "Closed queue %s", c.name)
+	}
+	return nil
+}
+
+func (c *RedisStreamMessageQueue) checkOpened(correlationId string) (state bool, err error) {
+	if !c.IsOpen() {
+		err = cerr.NewInvalidStateError(correlationId, "NOT_OPENED", "The queue is not opened")
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Clear method are clears component state by removing the stream with all its messages and groups.
+// The consumer group of this queue is created again.
+// Parameters:
+//  - correlationId 	(optional) transaction id to trace execution through call chain.
+// Returns: error or nil no errors occured.
+func (c *RedisStreamMessageQueue) Clear(correlationId string) error {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return err
+	}
+
+	if err = c.client.Del(c.key, c.key+":dead").Err(); err != nil {
+		return err
+	}
+	return c.createGroup(c.client)
+}
+
+// readLastDeliveredId reads the id of the last message delivered to the consumer group,
+// and the number of messages not delivered yet when Redis reports it.
+func (c *RedisStreamMessageQueue) readLastDeliveredId() (lastId string, lag int64, err error) {
+	cmd := redis.NewCmd("XINFO", "GROUPS", c.key)
+	c.client.Process(cmd)
+	groups, err := cmd.Result()
+	if err != nil {
+		return "", -1, err
+	}
+
+	values, _ := groups.([]interface{})
+	for _, value := range values {
+		fields, _ := value.([]interface{})
+		info := map[string]interface{}{}
+		for i := 0; i+1 < len(fields); i += 2 {
+			info[cconv.StringConverter.ToString(fields[i])] = fields[i+1]
+		}
+		if cconv.StringConverter.ToString(info["name"]) != c.group {
+			continue
+		}
+
+		lastId = cconv.StringConverter.ToString(info["last-delivered-id"])
+		lag = -1
+		if value, ok := info["lag"]; ok && value != nil {
+			lag = cconv.LongConverter.ToLong(value)
+		}
+		return lastId, lag, nil
+	}
+
+	return "0", -1, nil
+}
+
+// MessageCount method are reads the current number of messages in the stream
+// that were not delivered to the consumer group yet.
+// Returns: number of messages or error.
+func (c *RedisStreamMessageQueue) MessageCount() (count int64, err error) {
+	state, err := c.checkOpened("")
+	if !state {
+		return 0, err
+	}
+
+	lastId, lag, err := c.readLastDeliveredId()
+	if err != nil || lag >= 0 {
+		return lag, err
+	}
+
+	// Older Redis versions do not report the lag, so the messages are counted
+	messages, err := c.client.XRange(c.key, lastId, "+").Result()
+	if err != nil {
+		return 0, err
+	}
+	count = int64(len(messages))
+	if count > 0 && messages[0].ID == lastId {
+		count--
+	}
+	return count, nil
+}
+
+// Send method are sends a message into the queue.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - envelope          a message envelop to be sent.
+// Returns: error or nil for success.
+func (c *RedisStreamMessageQueue) Send(correlationId string, envelope *cqueues.MessageEnvelope) error {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return err
+	}
+
+	if envelope.MessageId == "" {
+		envelope.MessageId = cdata.IdGenerator.NextLong()
+	}
+	if envelope.CorrelationId == "" {
+		envelope.CorrelationId = correlationId
+	}
+	envelope.SentTime = time.Now().UTC()
+
+	if err = c.addMessage(c.key, envelope); err != nil {
+		return err
+	}
+
+	c.logger.Debug(envelope.CorrelationId, "Sent message %s via %s", envelope.String(), c.name)
+	return nil
+}
+
+// addMessage appends the message to the stream and trims the stream to its maximum length.
+func (c *RedisStreamMessageQueue) addMessage(stream string, envelope *cqueues.MessageEnvelope) error {
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+
+	return c.client.XAdd(&redis.XAddArgs{
+		Stream:       stream,
+		MaxLenApprox: c.maxLength,
+		Values:       map[string]interface{}{"envelope": data},
+	}).Err()
+}
+
+// SendAsObject method are sends an object into the queue.
+// Before sending the object is converted into JSON string and wrapped in a MessageEnvelope.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - messageType       a message type.
+//  - message           an object value to be sent.
+// Returns: error or nil for success.
+func (c *RedisStreamMessageQueue) SendAsObject(correlationId string, messageType string, message interface{}) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	envelope := cqueues.NewMessageEnvelope(correlationId, messageType, data)
+	return c.Send(correlationId, envelope)
+}
+
+// toEnvelope restores the message envelope from the stream entry and references the entry id.
+func (c *RedisStreamMessageQueue) toEnvelope(message redis.XMessage) (*cqueues.MessageEnvelope, error) {
+	envelope := cqueues.NewEmptyMessageEnvelope()
+	data := cconv.StringConverter.ToString(message.Values["envelope"])
+	if err := json.Unmarshal([]byte(data), envelope); err != nil {
+		return nil, err
+	}
+	envelope.SetReference(message.ID)
+	return envelope, nil
+}
+
+// Peek method are peeks a single incoming message from the queue without removing it.
+// If there are no messages available in the queue it returns nil.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+// Returns: a peeked message or error.
+func (c *RedisStreamMessageQueue) Peek(correlationId string) (result *cqueues.MessageEnvelope, err error) {
+	messages, err := c.PeekBatch(correlationId, 1)
+	if err != nil || len(messages) == 0 {
+		return nil, err
+	}
+	return messages[0], nil
+}
+
+// PeekBatch method are peeks multiple incoming messages that were not delivered to the consumer group yet.
+// If there are no messages available in the queue it returns an empty list.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - messageCount      a maximum number of messages to peek.
+// Returns: a list of peeked messages or error.
+func (c *RedisStreamMessageQueue) PeekBatch(correlationId string, messageCount int64) (result []*cqueues.MessageEnvelope, err error) {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return nil, err
+	}
+
+	result = []*cqueues.MessageEnvelope{}
+	if messageCount <= 0 {
+		return result, nil
+	}
+
+	lastId, _, err := c.readLastDeliveredId()
+	if err != nil {
+		return nil, err
+	}
+
+	// The range includes the last delivered message, so one more message is read
+	messages, err := c.client.XRangeN(c.key, lastId, "+", messageCount+1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, message := range messages {
+		if message.ID == lastId || int64(len(result)) >= messageCount {
+			continue
+		}
+		envelope, err := c.toEnvelope(message)
+		if err != nil {
+			return nil, err
+		}
+		// Peeked messages are not locked
+		envelope.SetReference(nil)
+		result = append(result, envelope)
+	}
+
+	c.logger.Trace(correlationId, "Peeked %d messages on %s", len(result), c.name)
+	return result, nil
+}
+
+// Receive method are receives an incoming message from the consumer group.
+// Messages pending longer than the claim timeout are claimed from other consumers first.
+// The message stays pending until it is completed.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - waitTimeout       a timeout to wait for a message to come.
+// Returns: a received message or nil if no messages came before the timeout, or error.
+func (c *RedisStreamMessageQueue) Receive(correlationId string, waitTimeout time.Duration) (result *cqueues.MessageEnvelope, err error) {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return nil, err
+	}
+
+	expireTime := time.Now().Add(waitTimeout)
+
+	for {
+		result, err = c.claimPending(correlationId)
+		if result != nil || err != nil {
+			return result, err
+		}
+
+		// Block in Redis by short intervals to claim pending messages in between
+		wait := time.Until(expireTime)
+		if wait <= 0 {
+			wait = -1
+		} else if wait < time.Millisecond {
+			// Redis blocks in milliseconds, and shorter waits would be truncated to 0 that blocks forever
+			wait = time.Millisecond
+		} else if wait > listenWaitTimeout {
+			wait = listenWaitTimeout
+		}
+
+		streams, err := c.client.XReadGroup(&redis.XReadGroupArgs{
+			Group:    c.group,
+			Consumer: c.consumer,
+			Streams:  []string{c.key, ">"},
+			Count:    1,
+			Block:    wait,
+		}).Result()
+		if err != nil && err != redis.Nil {
+			return nil, err
+		}
+
+		for _, stream := range streams {
+			for _, message := range stream.Messages {
+				result, err = c.toEnvelope(message)
+				if err != nil {
+					return nil, err
+				}
+				c.logger.Debug(result.CorrelationId, "Received message %s via %s", result.String(), c.name)
+				return result, nil
+			}
+		}
+
+		if !time.Now().Before(expireTime) {
+			return nil, nil
+		}
+	}
+}
+
+// claimPending claims a message pending longer than the claim timeout.
+// Messages delivered too many times are moved to the dead letter stream instead.
+func (c *RedisStreamMessageQueue) claimPending(correlationId string) (result *cqueues.MessageEnvelope, err error) {
+	pending, err := c.client.XPendingExt(&redis.XPendingExtArgs{
+		Stream: c.key,
+		Group:  c.group,
+		Start:  "-",
+		End:    "+",
+		Count:  streamClaimBatch,
+	}).Result()
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	claimTimeout := time.Duration(c.claimTimeout) * time.Millisecond
+	for _, entry := range pending {
+		if entry.Idle < claimTimeout {
+			continue
+		}
+
+		messages, err := c.client.XClaim(&redis.XClaimArgs{
+			Stream:   c.key,
+			Group:    c.group,
+			Consumer: c.consumer,
+			MinIdle:  claimTimeout,
+			Messages: []string{entry.Id},
+		}).Result()
+		if err != nil {
+			return nil, err
+		}
+		if len(messages) == 0 {
+			// The message was claimed by another consumer in between
+			continue
+		}
+
+		result, err = c.toEnvelope(messages[0])
+		if err != nil {
+			return nil, err
+		}
+
+		if c.maxDeliveries > 0 && entry.RetryCount >= c.maxDeliveries {
+			c.logger.Warn(result.CorrelationId, "Message %s was delivered %d times", result.MessageId, entry.RetryCount)
+			if err = c.MoveToDeadLetter(result); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		c.logger.Debug(result.CorrelationId, "Claimed message %s via %s", result.String(), c.name)
+		return result, nil
+	}
+	return nil, nil
+}
+
+// RenewLock method are renews a lock on a message by resetting its idle time,
+// so it is not claimed by other consumers.
+// Parameters:
+//  - message       a message to extend its lock.
+//  - lockTimeout   a locking timeout. It is not used, the lock lasts for the claim timeout.
+// Returns: error or nil for success.
+func (c *RedisStreamMessageQueue) RenewLock(message *cqueues.MessageEnvelope, lockTimeout time.Duration) error {
+	state, err := c.checkOpened(message.CorrelationId)
+	if !state {
+		return err
+	}
+
+	id, ok := message.GetReference().(string)
+	if !ok {
+		return nil
+	}
+
+	// Renew only messages that were not claimed by other consumers
+	pending, err := c.client.XPendingExt(&redis.XPendingExtArgs{
+		Stream:   c.key,
+		Group:    c.group,
+		Start:    id,
+		End:      id,
+		Count:    1,
+		Consumer: c.consumer,
+	}).Result()
+	if err != nil || len(pending) == 0 {
+		return err
+	}
+
+	cmd := redis.NewCmd("XCLAIM", c.key, c.group, c.consumer, 0, id, "JUSTID")
+	c.client.Process(cmd)
+	if err = cmd.Err(); err != nil {
+		return err
+	}
+
+	c.logger.Trace(message.CorrelationId, "Renewed lock for message %s at %s", message.MessageId, c.name)
+	return nil
+}
+
+// Complete method are acknowledges the message in the consumer group.
+// The message stays in the stream for other groups.
+// Parameters:
+//  - message   a message to remove.
+// Returns: error or nil for success.
+func (c *RedisStreamMessageQueue) Complete(message *cqueues.MessageEnvelope) error {
+	state, err := c.checkOpened(message.CorrelationId)
+	if !state {
+		return err
+	}
+
+	id, ok := message.GetReference().(string)
+	if !ok {
+		return nil
+	}
+
+	if err = c.client.XAck(c.key, c.group, id).Err(); err != nil {
+		return err
+	}
+	message.SetReference(nil)
+
+	c.logger.Trace(message.CorrelationId, "Completed message %s at %s", message.MessageId, c.name)
+	return nil
+}
+
+// Abandon method are makes the message available to other consumers of the group.
+// The message idle time is set to the claim timeout, so it is claimed on the next receive.
+// Parameters:
+//  - message   a message to return.
+// Returns: error or nil for success.
+func (c *RedisStreamMessageQueue) Abandon(message *cqueues.MessageEnvelope) error {
+	state, err := c.checkOpened(message.CorrelationId)
+	if !state {
+		return err
+	}
+
+	id, ok := message.GetReference().(string)
+	if !ok {
+		return nil
+	}
+
+	// Claiming with "JUSTID" does not count as another delivery
+	cmd := redis.NewCmd("XCLAIM", c.key, c.group, c.consumer, 0, id, "IDLE", c.claimTimeout, "JUSTID")
+	c.client.Process(cmd)
+	if err = cmd.Err(); err != nil {
+		return err
+	}
+	message.SetReference(nil)
+
+	c.logger.Trace(message.CorrelationId, "Abandoned message %s at %s", message.MessageId, c.name)
+	return nil
+}
+
+// MoveToDeadLetter method are acknowledges the message and copies it to the dead letter stream.
+// Parameters:
+//  - message   a message to be removed.
+// Returns: error or nil for success.
+func (c *RedisStreamMessageQueue) MoveToDeadLetter(message *cqueues.MessageEnvelope) error {
+	state, err := c.checkOpened(message.CorrelationId)
+	if !state {
+		return err
+	}
+
+	id, ok := message.GetReference().(string)
+	if !ok {
+		return nil
+	}
+
+	if err = c.addMessage(c.key+":dead", message); err != nil {
+		return err
+	}
+	if err = c.client.XAck(c.key, c.group, id).Err(); err != nil {
+		return err
+	}
+	message.SetReference(nil)
+
+	c.logger.Trace(message.CorrelationId, "Moved to dead message %s at %s", message.MessageId, c.name)
+	return nil
+}
+
+// ReadDeadLetterCount method are reads the number of messages moved to the dead letter stream.
+// Returns: number of messages or error.
+func (c *RedisStreamMessageQueue) ReadDeadLetterCount() (count int64, err error) {
+	state, err := c.checkOpened("")
+	if !state {
+		return 0, err
+	}
+
+	return c.client.XLen(c.key + ":dead").Result()
+}
+
+// Listen method are listens for incoming messages and blocks the current thread until queue is closed.
+// Messages that failed to be processed are claimed again after the claim timeout.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - receiver          a receiver to receive incoming messages.
+// Returns: error or nil for success.
+func (c *RedisStreamMessageQueue) Listen(correlationId string, receiver cqueues.IMessageReceiver) error {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return err
+	}
+
+	return listenMessages(correlationId, c, receiver, &c.cancel, c.logger)
+}
+
+// BeginListen method are listens for incoming messages without blocking the current thread.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - receiver          a receiver to receive incoming messages.
+func (c *RedisStreamMessageQueue) BeginListen(correlationId string, receiver cqueues.IMessageReceiver) {
+	go func() {
+		err := c.Listen(correlationId, receiver)
+		if err != nil {
+			c.logger.Error(correlationId, err, "Failed to listen messages at %s", c.name)
+		}
+	}()
+}
+
+// EndListen method are ends listening for incoming messages.
+// When this method is call Listen unblocks the thread and execution continues.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+func (c *RedisStreamMessageQueue) EndListen(correlationId string) {
+	atomic.StoreInt32(&c.cancel, 1)
+}
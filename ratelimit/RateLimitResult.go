@@ -0,0 +1,15 @@
+package ratelimit
+
+/*
+RateLimitResult are outcome of a rate limit check.
+
+See RedisRateLimiter
+*/
+type RateLimitResult struct {
+	// Allowed is true when the request fits into the limit.
+	Allowed bool `json:"allowed"`
+	// Remaining is a number of requests that can still be made within the limit.
+	Remaining int64 `json:"remaining"`
+	// RetryAfter is a time in milliseconds after which a denied request can be retried, or 0 if it was allowed.
+	RetryAfter int64 `json:"retry_after"`
+}
@@ -0,0 +1,333 @@
+package ratelimit
+
+import (
+	"sync"
+
+	"github.com/go-redis/redis"
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cconv "github.com/pip-services3-go/pip-services3-commons-go/convert"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	cerr "github.com/pip-services3-go/pip-services3-commons-go/errors"
+	cref "github.com/pip-services3-go/pip-services3-commons-go/refer"
+	redisconn "github.com/pip-services3-go/pip-services3-redis-go/connect"
+)
+
+const (
+	// FixedWindow algorithm counts requests in consecutive time windows of the interval length.
+	FixedWindow = "fixed-window"
+	// SlidingWindow algorithm keeps a log of requests made within the last interval.
+	SlidingWindow = "sliding-window"
+	// TokenBucket algorithm refills the limit evenly over the interval and allows bursts up to the limit.
+	TokenBucket = "token-bucket"
+)
+
+/*
+RedisRateLimiter are distributed rate limiter that is implemented based on Redis in-memory database.
+
+All instances share the same limits, so every key is allowed a limited number of requests
+per interval regardless of the number of replicas. Each check is performed atomically
+by a Lua script using the Redis server clock.
+
+Supported algorithms:
+  - fixed-window:    counts requests in consecutive windows. Cheap, but allows bursts on window edges.
+  - sliding-window:  keeps a log of requests within the last interval. Exact, but uses memory for every request.
+  - token-bucket:    refills the limit evenly over the interval and allows bursts up to the limit.
+
+Configuration parameters:
+
+  - connection(s):
+    - discovery_key:         (optional) a key to retrieve the connection from IDiscovery
+    - host:                  host name or IP address
+    - port:                  port number
+    - uri:                   resource URI or connection string with all parameters in it
+  - credential(s):
+    - store_key:             key to retrieve parameters from credential store
+    - username:              user name (currently is not used)
+    - password:              user password
+  - limits:
+    - [key]:                 number of requests per interval for the specific key
+  - options:
+    - algorithm:             rate limiting algorithm: fixed-window, sliding-window or token-bucket (default: fixed-window)
+    - limit:                 default number of requests per interval (default: 100)
+    - interval:              interval in milliseconds (default: 60000)
+    - timeout:               connection timeout in milliseconds (default: 30000)
+    - db_num:                database number in Redis  (default 0)
+    - cluster:               enable redis cluster
+
+References:
+
+- *:discovery:*:*:1.0        (optional) IDiscovery services to resolve connection
+- *:credential-store:*:*:1.0 (optional) Credential stores to resolve credential
+
+Example:
+
+    limiter = NewRedisRateLimiter();
+    limiter.Configure(cconf.NewConfigParamsFromTuples(
+      "host", "localhost",
+      "port", 6379,
+      "options.algorithm", "sliding-window",
+      "limits.tenant_1", 1000,
+    ));
+
+    err = limiter.Open("123")
+      ...
+
+    result, err := limiter.Allow("123", "tenant_1", 1)
+    if err == nil && !result.Allowed {
+    	// Reject the request and ask to retry after result.RetryAfter milliseconds...
+    }
+*/
+type RedisRateLimiter struct {
+	connectionResolver *redisconn.RedisConnectionResolver
+
+	algorithm    string
+	interval     int64
+	defaultLimit int64
+	limits       map[string]int64
+	limitsMx     sync.Mutex
+
+	client redis.UniversalClient
+}
+
+// NewRedisRateLimiter method are creates a new instance of this rate limiter.
+func NewRedisRateLimiter() *RedisRateLimiter {
+	c := &RedisRateLimiter{
+		connectionResolver: redisconn.NewRedisConnectionResolver(),
+		algorithm:          FixedWindow,
+		interval:           60000,
+		defaultLimit:       100,
+		limits:             map[string]int64{},
+		client:             nil,
+	}
+	return c
+}
+
+// Configure method are configures component by passing configuration parameters.
+// Parameters:
+//   - config    configuration parameters to be set.
+func (c *RedisRateLimiter) Configure(config *cconf.ConfigParams) {
+	c.connectionResolver.Configure(config)
+
+	c.algorithm = config.GetAsStringWithDefault("options.algorithm", c.algorithm)
+	c.interval = config.GetAsLongWithDefault("options.interval", c.interval)
+	c.defaultLimit = config.GetAsLongWithDefault("options.limit", c.defaultLimit)
+
+	limits := config.GetSection("limits")
+	for _, key := range limits.Keys() {
+		c.SetLimit(key, limits.GetAsLongWithDefault(key, c.defaultLimit))
+	}
+}
+
+// SetReferences method are sets references to dependent components.
+// Parameters:
+//   - references 	references to locate the component dependencies.
+func (c *RedisRateLimiter) SetReferences(references cref.IReferences) {
+	c.connectionResolver.SetReferences(references)
+}
+
+// SetLimit method are sets a number of requests per interval for the specific key.
+// Parameters:
+//   - key       a unique rate limit key.
+//   - limit     a maximum number of requests per interval.
+func (c *RedisRateLimiter) SetLimit(key string, limit int64) {
+	c.limitsMx.Lock()
+	defer c.limitsMx.Unlock()
+
+	c.limits[key] = limit
+}
+
+// GetLimit method are gets a number of requests per interval for the specific key.
+// Parameters:
+//   - key       a unique rate limit key.
+// Returns: a maximum number of requests per interval.
+func (c *RedisRateLimiter) GetLimit(key string) int64 {
+	c.limitsMx.Lock()
+	defer c.limitsMx.Unlock()
+
+	if limit, ok := c.limits[key]; ok {
+		return limit
+	}
+	return c.defaultLimit
+}
+
+// IsOpen method are checks if the component is opened.
+// Returns true if the component has been opened and false otherwise.
+func (c *RedisRateLimiter) IsOpen() bool {
+	return c.client != nil
+}
+
+// Open method are opens the component.
+// Parameters:
+// 	- correlationId 	(optional) transaction id to trace execution through call chain.
+// Returns: error or nil no errors occured.
+func (c *RedisRateLimiter) Open(correlationId string) error {
+	if c.algorithm != FixedWindow && c.algorithm != SlidingWindow && c.algorithm != TokenBucket {
+		err := cerr.NewConfigError(
+			correlationId,
+			"WRONG_ALGORITHM",
+			"Rate limiting algorithm "+c.algorithm+" is not supported",
+		).WithDetails("algorithm", c.algorithm)
+		return err
+	}
+
+	client, err := c.connectionResolver.Connect(correlationId)
+	if err != nil {
+		return err
+	}
+
+	c.client = client
+	return nil
+}
+
+// Close method are closes component and frees used resources.
+// Parameters:
+//  - correlationId 	(optional) transaction id to trace execution through call chain.
+// Retruns: error or nil no errors occured.
+func (c *RedisRateLimiter) Close(correlationId string) error {
+	if c.client != nil {
+		err := c.client.Close()
+		c.client = nil
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *RedisRateLimiter) checkOpened(correlationId string) (state bool, err error) {
+	if !c.IsOpen() {
+		err = cerr.NewInvalidStateError(correlationId, "NOT_OPENED", "Connection is not opened")
+		return false, err
+	}
+
+	return true, nil
+}
+
+// All scripts take the cost, the limit and the interval in milliseconds
+// and return {allowed, remaining, retry after}.
+
+// fixedWindowScript counts requests of the current window in a hash with "window" and "used" fields.
+// The counter is reset when a new window starts, and the key expires with the window.
+var fixedWindowScript = redis.NewScript(`
+redis.replicate_commands()
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local cost = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+local interval = tonumber(ARGV[3])
+local window = math.floor(now / interval)
+local state = redis.call("HMGET", KEYS[1], "window", "used")
+local used = 0
+if tonumber(state[1]) == window then
+	used = tonumber(state[2]) or 0
+end
+if used + cost > limit then
+	return {0, limit - used, (window + 1) * interval - now}
+end
+used = used + cost
+redis.call("HMSET", KEYS[1], "window", window, "used", used)
+redis.call("PEXPIRE", KEYS[1], (window + 1) * interval - now)
+return {1, limit - used, 0}
+`)
+
+// slidingWindowScript keeps a sorted set with an "<id>:<n>" entry for every counted request
+// scored by its time, so the number of requests within the interval is the set cardinality.
+var slidingWindowScript = redis.NewScript(`
+redis.replicate_commands()
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local cost = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+local interval = tonumber(ARGV[3])
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - interval)
+local used = redis.call("ZCARD", KEYS[1])
+if used + cost > limit then
+	local oldest = redis.call("ZRANGE", KEYS[1], used + cost - limit - 1, used + cost - limit - 1, "WITHSCORES")
+	return {0, limit - used, tonumber(oldest[2]) + interval - now}
+end
+for i = 1, cost do
+	redis.call("ZADD", KEYS[1], now, ARGV[4] .. ":" .. i)
+end
+redis.call("PEXPIRE", KEYS[1], interval)
+return {1, limit - used - cost, 0}
+`)
+
+// tokenBucketScript keeps the number of tokens and the time they were refilled in a hash.
+var tokenBucketScript = redis.NewScript(`
+redis.replicate_commands()
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local cost = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+local interval = tonumber(ARGV[3])
+local rate = limit / interval
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "time")
+local tokens = tonumber(bucket[1]) or limit
+local last = tonumber(bucket[2]) or now
+tokens = math.min(limit, tokens + math.max(0, now - last) * rate)
+local allowed = 0
+local retry = 0
+if tokens >= cost then
+	tokens = tokens - cost
+	allowed = 1
+else
+	retry = math.ceil((cost - tokens) / rate)
+end
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "time", now)
+redis.call("PEXPIRE", KEYS[1], math.ceil((limit - tokens) / rate) + 1)
+return {allowed, math.floor(tokens), retry}
+`)
+
+// Allow method are checks if a request with the given cost fits into the limit for the key
+// and counts it when it does.
+// Parameters:
+//  - correlationId     (optional) transaction id to trace execution through call chain.
+//  - key               a unique rate limit key, like a tenant id.
+//  - cost              a number of requests to count, usually 1.
+// Returns: a check result or error.
+func (c *RedisRateLimiter) Allow(correlationId string, key string, cost int64) (result *RateLimitResult, err error) {
+	state, err := c.checkOpened(correlationId)
+	if !state {
+		return nil, err
+	}
+
+	limit := c.GetLimit(key)
+	if cost <= 0 || cost > limit {
+		err = cerr.NewBadRequestError(
+			correlationId,
+			"WRONG_COST",
+			"Request cost must be positive and must not exceed the limit",
+		).WithDetails("key", key).WithDetails("cost", cost).WithDetails("limit", limit)
+		return nil, err
+	}
+
+	keys := []string{"ratelimit:" + key}
+	var res interface{}
+	switch c.algorithm {
+	case SlidingWindow:
+		res, err = slidingWindowScript.Run(c.client, keys, cost, limit, c.interval, cdata.IdGenerator.NextLong()).Result()
+	case TokenBucket:
+		res, err = tokenBucketScript.Run(c.client, keys, cost, limit, c.interval).Result()
+	default:
+		res, err = fixedWindowScript.Run(c.client, keys, cost, limit, c.interval).Result()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	values, _ := res.([]interface{})
+	if len(values) < 3 {
+		err = cerr.NewInternalError(correlationId, "WRONG_RESULT", "Rate limiting script returned unexpected result")
+		return nil, err
+	}
+
+	result = &RateLimitResult{
+		Allowed:    cconv.LongConverter.ToLong(values[0]) == 1,
+		Remaining:  cconv.LongConverter.ToLong(values[1]),
+		RetryAfter: cconv.LongConverter.ToLong(values[2]),
+	}
+	if result.Remaining < 0 {
+		result.Remaining = 0
+	}
+	return result, nil
+}
@@ -0,0 +1,115 @@
+package test_auth
+
+import (
+	"os"
+	"testing"
+
+	"github.com/go-redis/redis"
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	cauth "github.com/pip-services3-go/pip-services3-components-go/auth"
+	redisauth "github.com/pip-services3-go/pip-services3-redis-go/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisCredentialStore(t *testing.T) {
+	host := os.Getenv("REDIS_SERVICE_HOST")
+	if host == "" {
+		host = "localhost"
+	}
+
+	port := os.Getenv("REDIS_SERVICE_PORT")
+	if port == "" {
+		port = "6379"
+	}
+
+	namespace := "credentials:" + cdata.IdGenerator.NextShort()
+
+	store := redisauth.NewRedisCredentialStore()
+	store.Configure(cconf.NewConfigParamsFromTuples(
+		"connection.host", host,
+		"connection.port", port,
+		"options.namespace", namespace,
+	))
+	err := store.Open("")
+	assert.Nil(t, err)
+	defer store.Close("")
+
+	encryptedStore := redisauth.NewRedisCredentialStore()
+	encryptedStore.Configure(cconf.NewConfigParamsFromTuples(
+		"connection.host", host,
+		"connection.port", port,
+		"options.namespace", namespace,
+		"options.master_key", "Gekn+guoR/d0HUcEsBHalDvPQq9MVGy9tCM9FFjv1LY=",
+	))
+	err = encryptedStore.Open("")
+	assert.Nil(t, err)
+	defer encryptedStore.Close("")
+
+	t.Run("Store Lookup", func(t *testing.T) {
+		err := store.Store("", "key1", cauth.NewCredentialParamsFromTuples(
+			"username", "jdoe",
+			"password", "pass123",
+		))
+		assert.Nil(t, err)
+
+		credential, err := store.Lookup("", "key1")
+		assert.Nil(t, err)
+		assert.NotNil(t, credential)
+		assert.Equal(t, "jdoe", credential.Username())
+		assert.Equal(t, "pass123", credential.Password())
+
+		// Removing the credential
+		err = store.Store("", "key1", nil)
+		assert.Nil(t, err)
+
+		credential, err = store.Lookup("", "key1")
+		assert.Nil(t, err)
+		assert.Nil(t, credential)
+	})
+
+	t.Run("Encrypted Credentials", func(t *testing.T) {
+		err := encryptedStore.Store("", "key2", cauth.NewCredentialParamsFromTuples(
+			"access_id", "AKIA1",
+			"access_key", "secret",
+		))
+		assert.Nil(t, err)
+
+		credential, err := encryptedStore.Lookup("", "key2")
+		assert.Nil(t, err)
+		assert.NotNil(t, credential)
+		assert.Equal(t, "AKIA1", credential.AccessId())
+		assert.Equal(t, "secret", credential.AccessKey())
+
+		// Encrypted credentials can't be read without the master key
+		_, err = store.Lookup("", "key2")
+		assert.NotNil(t, err)
+
+		// Encrypted credentials can't be moved to other keys
+		client := redis.NewClient(&redis.Options{Addr: host + ":" + port})
+		defer client.Close()
+		value, err := client.Get(namespace + ":key2").Result()
+		assert.Nil(t, err)
+		err = client.Set(namespace+":key3", value, 0).Err()
+		assert.Nil(t, err)
+
+		_, err = encryptedStore.Lookup("", "key3")
+		assert.NotNil(t, err)
+
+		encryptedStore.Store("", "key2", nil)
+		encryptedStore.Store("", "key3", nil)
+	})
+
+	t.Run("Invalid Master Key", func(t *testing.T) {
+		// Passwords are not accepted as master keys
+		invalidStore := redisauth.NewRedisCredentialStore()
+		invalidStore.Configure(cconf.NewConfigParamsFromTuples(
+			"connection.host", host,
+			"connection.port", port,
+			"options.master_key", "master123",
+		))
+		err := invalidStore.Open("")
+		assert.NotNil(t, err)
+		assert.False(t, invalidStore.IsOpen())
+	})
+}
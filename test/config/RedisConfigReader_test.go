@@ -0,0 +1,80 @@
+package test_config
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	crun "github.com/pip-services3-go/pip-services3-commons-go/run"
+	redisconfig "github.com/pip-services3-go/pip-services3-redis-go/config"
+	"github.com/stretchr/testify/assert"
+)
+
+type changeListener struct {
+	changes chan string
+}
+
+func (c *changeListener) Notify(correlationId string, args *crun.Parameters) {
+	c.changes <- correlationId
+}
+
+func TestRedisConfigReader(t *testing.T) {
+	host := os.Getenv("REDIS_SERVICE_HOST")
+	if host == "" {
+		host = "localhost"
+	}
+
+	port := os.Getenv("REDIS_SERVICE_PORT")
+	if port == "" {
+		port = "6379"
+	}
+
+	for _, format := range []string{redisconfig.HashFormat, redisconfig.JsonFormat} {
+		t.Run(format, func(t *testing.T) {
+			reader := redisconfig.NewRedisConfigReader()
+			reader.Configure(cconf.NewConfigParamsFromTuples(
+				"connection.host", host,
+				"connection.port", port,
+				"options.key", "config:"+cdata.IdGenerator.NextLong(),
+				"options.format", format,
+				"parameters.RETRIES", 3,
+			))
+
+			err := reader.Open("")
+			assert.Nil(t, err)
+			defer reader.Close("")
+
+			listener := &changeListener{changes: make(chan string, 10)}
+			reader.AddChangeListener(listener)
+
+			config, err := reader.ReadConfig("", nil)
+			assert.Nil(t, err)
+			assert.Equal(t, 0, config.Len())
+
+			err = reader.WriteConfig("123", cconf.NewConfigParamsFromTuples(
+				"options.timeout", "{{TIMEOUT}}",
+				"options.retries", "{{RETRIES}}",
+			))
+			assert.Nil(t, err)
+
+			select {
+			case correlationId := <-listener.changes:
+				assert.Equal(t, "123", correlationId)
+			case <-time.After(3000 * time.Millisecond):
+				assert.Fail(t, "Change notification was not received")
+			}
+
+			config, err = reader.ReadConfig("", cconf.NewConfigParamsFromTuples("TIMEOUT", 5000))
+			assert.Nil(t, err)
+			assert.Equal(t, int64(5000), config.GetAsLong("options.timeout"))
+			assert.Equal(t, 3, config.GetAsInteger("options.retries"))
+
+			// Templates are kept as they are without parameters
+			config, err = reader.ReadConfig("", nil)
+			assert.Nil(t, err)
+			assert.Equal(t, "{{TIMEOUT}}", config.GetAsString("options.timeout"))
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package test_connect
+
+import (
+	"testing"
+	"time"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	redisconn "github.com/pip-services3-go/pip-services3-redis-go/connect"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisConnectionResolver(t *testing.T) {
+	t.Run("Resolve Host And Port", func(t *testing.T) {
+		resolver := redisconn.NewRedisConnectionResolver()
+		resolver.Configure(cconf.NewConfigParamsFromTuples(
+			"connection.host", "redis1",
+			"connection.port", 6380,
+			"credential.password", "pass123",
+			"options.db_num", 2,
+			"options.timeout", 5000,
+		))
+
+		options, err := resolver.Resolve("")
+		assert.Nil(t, err)
+		assert.Equal(t, "redis1:6380", options.Addr)
+		assert.Equal(t, "pass123", options.Password)
+		assert.Equal(t, 2, options.DB)
+		assert.Equal(t, 5000*time.Millisecond, options.DialTimeout)
+	})
+
+	t.Run("Resolve Uri", func(t *testing.T) {
+		resolver := redisconn.NewRedisConnectionResolver()
+		resolver.Configure(cconf.NewConfigParamsFromTuples(
+			"connection.uri", "redis://:pass123@redis1:6380/3",
+		))
+
+		options, err := resolver.Resolve("")
+		assert.Nil(t, err)
+		assert.Equal(t, "redis1:6380", options.Addr)
+		assert.Equal(t, "pass123", options.Password)
+		assert.Equal(t, 3, options.DB)
+	})
+
+	t.Run("Resolve Wrong Uri", func(t *testing.T) {
+		resolver := redisconn.NewRedisConnectionResolver()
+		resolver.Configure(cconf.NewConfigParamsFromTuples(
+			"connection.uri", "http://redis1:6380",
+		))
+
+		_, err := resolver.Resolve("")
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,92 @@
+package test_connect
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	ccon "github.com/pip-services3-go/pip-services3-components-go/connect"
+	redisconn "github.com/pip-services3-go/pip-services3-redis-go/connect"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisDiscovery(t *testing.T) {
+	host := os.Getenv("REDIS_SERVICE_HOST")
+	if host == "" {
+		host = "localhost"
+	}
+
+	port := os.Getenv("REDIS_SERVICE_PORT")
+	if port == "" {
+		port = "6379"
+	}
+
+	config := cconf.NewConfigParamsFromTuples(
+		"connection.host", host,
+		"connection.port", port,
+		"options.ttl", 1000,
+		"options.heartbeat_interval", 300,
+	)
+	key := "service_" + cdata.IdGenerator.NextLong()
+
+	discovery1 := redisconn.NewRedisDiscovery()
+	discovery1.Configure(config)
+
+	// Registrations made before opening are sent on open
+	_, err := discovery1.Register("", key, ccon.NewConnectionParamsFromTuples(
+		"host", "10.1.1.100",
+		"port", 8080,
+	))
+	assert.Nil(t, err)
+
+	err = discovery1.Open("")
+	assert.Nil(t, err)
+	defer discovery1.Close("")
+
+	discovery2 := redisconn.NewRedisDiscovery()
+	discovery2.Configure(config)
+	err = discovery2.Open("")
+	assert.Nil(t, err)
+
+	t.Run("Register Resolve", func(t *testing.T) {
+		_, err := discovery2.Register("", key, ccon.NewConnectionParamsFromTuples(
+			"host", "10.1.1.101",
+			"port", 8080,
+		))
+		assert.Nil(t, err)
+
+		connections, err := discovery1.ResolveAll("", key)
+		assert.Nil(t, err)
+		assert.Len(t, connections, 2)
+
+		connection, err := discovery1.ResolveOne("", key)
+		assert.Nil(t, err)
+		assert.NotNil(t, connection)
+		assert.Equal(t, 8080, connection.Port())
+	})
+
+	t.Run("Heartbeat Registrations", func(t *testing.T) {
+		// Registrations are renewed while the component is open
+		time.Sleep(1500 * time.Millisecond)
+
+		connections, err := discovery2.ResolveAll("", key)
+		assert.Nil(t, err)
+		assert.Len(t, connections, 2)
+
+		err = discovery2.Close("")
+		assert.Nil(t, err)
+
+		connections, err = discovery1.ResolveAll("", key)
+		assert.Nil(t, err)
+		assert.Len(t, connections, 1)
+		assert.Equal(t, "10.1.1.100", connections[0].Host())
+	})
+
+	t.Run("Resolve Missing", func(t *testing.T) {
+		connection, err := discovery1.ResolveOne("", "missing_"+key)
+		assert.Nil(t, err)
+		assert.Nil(t, connection)
+	})
+}
@@ -0,0 +1,107 @@
+package test_count
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	ccount "github.com/pip-services3-go/pip-services3-components-go/count"
+	rediscount "github.com/pip-services3-go/pip-services3-redis-go/count"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisCounters(t *testing.T) {
+	host := os.Getenv("REDIS_SERVICE_HOST")
+	if host == "" {
+		host = "localhost"
+	}
+
+	port := os.Getenv("REDIS_SERVICE_PORT")
+	if port == "" {
+		port = "6379"
+	}
+
+	config := cconf.NewConfigParamsFromTuples(
+		"connection.host", host,
+		"connection.port", port,
+		"interval", 0,
+		"options.prefix", "counters:"+cdata.IdGenerator.NextShort(),
+	)
+
+	// Two instances share the same counters
+	counters1 := rediscount.NewRedisCounters()
+	counters1.Configure(config)
+	err := counters1.Open("")
+	assert.Nil(t, err)
+	defer counters1.Close("")
+
+	counters2 := rediscount.NewRedisCounters()
+	counters2.Configure(config)
+	err = counters2.Open("")
+	assert.Nil(t, err)
+	defer counters2.Close("")
+
+	t.Run("Aggregate Counters", func(t *testing.T) {
+		counters1.Increment("test.calls", 2)
+		counters2.Increment("test.calls", 3)
+		counters1.Stats("test.stats", 1)
+		counters2.Stats("test.stats", 3)
+		counters2.Last("test.last", 5)
+
+		err := counters1.Dump()
+		assert.Nil(t, err)
+		err = counters2.Dump()
+		assert.Nil(t, err)
+
+		// Only changes are added on repeated flushes
+		counters1.IncrementOne("test.calls")
+		err = counters1.Dump()
+		assert.Nil(t, err)
+
+		result, err := counters1.ReadCounters("")
+		assert.Nil(t, err)
+		values := map[string]*ccount.Counter{}
+		for _, counter := range result {
+			values[counter.Name] = counter
+		}
+
+		assert.NotNil(t, values["test.calls"])
+		assert.Equal(t, ccount.Increment, values["test.calls"].Type)
+		assert.Equal(t, 6, values["test.calls"].Count)
+
+		assert.NotNil(t, values["test.stats"])
+		assert.Equal(t, 2, values["test.stats"].Count)
+		assert.Equal(t, float32(1), values["test.stats"].Min)
+		assert.Equal(t, float32(3), values["test.stats"].Max)
+		assert.Equal(t, float32(2), values["test.stats"].Average)
+
+		assert.NotNil(t, values["test.last"])
+		assert.Equal(t, float32(5), values["test.last"].Last)
+
+		result, err = counters2.ReadIntervalCounters("", time.Now())
+		assert.Nil(t, err)
+		assert.Len(t, result, 3)
+	})
+
+	t.Run("Flush Reset Counters", func(t *testing.T) {
+		counters1.Increment("test.resets", 3)
+		err := counters1.Dump()
+		assert.Nil(t, err)
+
+		// Reset counters are flushed from the start even when they grow above the previous values
+		counters1.ClearAll()
+		counters1.Increment("test.resets", 4)
+		err = counters1.Dump()
+		assert.Nil(t, err)
+
+		result, err := counters1.ReadCounters("")
+		assert.Nil(t, err)
+		for _, counter := range result {
+			if counter.Name == "test.resets" {
+				assert.Equal(t, 7, counter.Count)
+			}
+		}
+	})
+}
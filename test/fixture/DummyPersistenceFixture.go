@@ -0,0 +1,119 @@
+package test_fixture
+
+import (
+	"testing"
+
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	"github.com/stretchr/testify/assert"
+)
+
+type DummyPersistenceFixture struct {
+	dummy1      Dummy
+	dummy2      Dummy
+	persistence IDummyPersistence
+}
+
+func NewDummyPersistenceFixture(persistence IDummyPersistence) *DummyPersistenceFixture {
+	c := DummyPersistenceFixture{}
+	c.dummy1 = Dummy{Id: "", Key: "Key 1", Content: "Content 1"}
+	c.dummy2 = Dummy{Id: "", Key: "Key 2", Content: "Content 2"}
+	c.persistence = persistence
+	return &c
+}
+
+func (c *DummyPersistenceFixture) TestCrudOperations(t *testing.T) {
+	var dummy1 Dummy
+	var dummy2 Dummy
+
+	result, err := c.persistence.Create("", c.dummy1)
+	assert.Nil(t, err)
+	assert.NotNil(t, result)
+	dummy1 = *result
+	assert.NotEqual(t, "", dummy1.Id)
+	assert.Equal(t, c.dummy1.Key, dummy1.Key)
+	assert.Equal(t, c.dummy1.Content, dummy1.Content)
+
+	result, err = c.persistence.Create("", c.dummy2)
+	assert.Nil(t, err)
+	assert.NotNil(t, result)
+	dummy2 = *result
+	assert.NotEqual(t, "", dummy2.Id)
+	assert.Equal(t, c.dummy2.Key, dummy2.Key)
+	assert.Equal(t, c.dummy2.Content, dummy2.Content)
+
+	// Creating an item with the same id fails
+	_, err = c.persistence.Create("", dummy1)
+	assert.NotNil(t, err)
+
+	page, err := c.persistence.GetPageByFilter("", cdata.NewEmptyFilterParams(), cdata.NewPagingParams(0, 10, true))
+	assert.Nil(t, err)
+	assert.NotNil(t, page)
+	assert.Len(t, page.Data, 2)
+	assert.Equal(t, int64(2), *page.Total)
+
+	count, err := c.persistence.GetCountByFilter("", cdata.NewFilterParamsFromTuples("key", "Key 1"))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), count)
+
+	// Update the dummy
+	dummy1.Content = "Updated Content 1"
+	result, err = c.persistence.Update("", dummy1)
+	assert.Nil(t, err)
+	assert.NotNil(t, result)
+	assert.Equal(t, dummy1.Id, result.Id)
+	assert.Equal(t, dummy1.Key, result.Key)
+	assert.Equal(t, dummy1.Content, result.Content)
+
+	// Updating a missing item does nothing
+	result, err = c.persistence.Update("", Dummy{Id: "missing", Key: "Key 3"})
+	assert.Nil(t, err)
+	assert.Nil(t, result)
+
+	// Set the dummy
+	dummy1.Content = "Updated Content 2"
+	result, err = c.persistence.Set("", dummy1)
+	assert.Nil(t, err)
+	assert.NotNil(t, result)
+	assert.Equal(t, dummy1.Id, result.Id)
+	assert.Equal(t, dummy1.Key, result.Key)
+	assert.Equal(t, dummy1.Content, result.Content)
+
+	// Partially update the dummy
+	result, err = c.persistence.UpdatePartially("", dummy1.Id,
+		cdata.NewAnyValueMapFromTuples("content", "Partially Updated Content 1"))
+	assert.Nil(t, err)
+	assert.NotNil(t, result)
+	assert.Equal(t, dummy1.Id, result.Id)
+	assert.Equal(t, dummy1.Key, result.Key)
+	assert.Equal(t, "Partially Updated Content 1", result.Content)
+
+	// Get the dummy by Id
+	result, err = c.persistence.GetOneById("", dummy1.Id)
+	assert.Nil(t, err)
+	assert.NotNil(t, result)
+	assert.Equal(t, dummy1.Id, result.Id)
+	assert.Equal(t, "Partially Updated Content 1", result.Content)
+
+	items, err := c.persistence.GetListByIds("", []string{dummy1.Id, dummy2.Id, "missing"})
+	assert.Nil(t, err)
+	assert.Len(t, items, 2)
+
+	// Delete the dummy
+	result, err = c.persistence.DeleteById("", dummy1.Id)
+	assert.Nil(t, err)
+	assert.NotNil(t, result)
+	assert.Equal(t, dummy1.Id, result.Id)
+
+	// Try to get deleted dummy
+	result, err = c.persistence.GetOneById("", dummy1.Id)
+	assert.Nil(t, err)
+	assert.Nil(t, result)
+
+	// Delete all remaining dummies
+	err = c.persistence.DeleteByIds("", []string{dummy2.Id})
+	assert.Nil(t, err)
+
+	count, err = c.persistence.GetCountByFilter("", cdata.NewEmptyFilterParams())
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), count)
+}
@@ -0,0 +1,23 @@
+package test_fixture
+
+import (
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+)
+
+type IDummyPersistence interface {
+	GetPageByFilter(correlationId string, filter *cdata.FilterParams, paging *cdata.PagingParams) (page *DummyPage, err error)
+	GetCountByFilter(correlationId string, filter *cdata.FilterParams) (count int64, err error)
+	GetListByIds(correlationId string, ids []string) (items []Dummy, err error)
+	GetOneById(correlationId string, id string) (item *Dummy, err error)
+	Create(correlationId string, item Dummy) (result *Dummy, err error)
+	Update(correlationId string, item Dummy) (result *Dummy, err error)
+	Set(correlationId string, item Dummy) (result *Dummy, err error)
+	UpdatePartially(correlationId string, id string, data *cdata.AnyValueMap) (item *Dummy, err error)
+	DeleteById(correlationId string, id string) (item *Dummy, err error)
+	DeleteByIds(correlationId string, ids []string) (err error)
+}
+
+type DummyPage struct {
+	Total *int64  `json:"total"`
+	Data  []Dummy `json:"data"`
+}
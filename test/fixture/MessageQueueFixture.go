@@ -0,0 +1,180 @@
+package test_fixture
+
+import (
+	"testing"
+	"time"
+
+	cqueues "github.com/pip-services3-go/pip-services3-messaging-go/queues"
+	"github.com/stretchr/testify/assert"
+)
+
+type MessageQueueFixture struct {
+	queue cqueues.IMessageQueue
+}
+
+func NewMessageQueueFixture(queue cqueues.IMessageQueue) *MessageQueueFixture {
+	c := MessageQueueFixture{}
+	c.queue = queue
+	return &c
+}
+
+func (c *MessageQueueFixture) TestSendReceiveMessage(t *testing.T) {
+	envelope1 := cqueues.NewMessageEnvelope("123", "Test", []byte("Test message"))
+	err := c.queue.Send("", envelope1)
+	assert.Nil(t, err)
+
+	count, err := c.queue.MessageCount()
+	assert.Nil(t, err)
+	assert.True(t, count > 0)
+
+	envelope2, err := c.queue.Receive("", 10000*time.Millisecond)
+	assert.Nil(t, err)
+	assert.NotNil(t, envelope2)
+	assert.Equal(t, envelope1.MessageType, envelope2.MessageType)
+	assert.Equal(t, envelope1.Message, envelope2.Message)
+	assert.Equal(t, envelope1.CorrelationId, envelope2.CorrelationId)
+
+	err = c.queue.Complete(envelope2)
+	assert.Nil(t, err)
+}
+
+func (c *MessageQueueFixture) TestReceiveSendMessage(t *testing.T) {
+	envelope1 := cqueues.NewMessageEnvelope("123", "Test", []byte("Test message"))
+
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+		c.queue.Send("", envelope1)
+	}()
+
+	envelope2, err := c.queue.Receive("", 10000*time.Millisecond)
+	assert.Nil(t, err)
+	assert.NotNil(t, envelope2)
+	assert.Equal(t, envelope1.MessageType, envelope2.MessageType)
+	assert.Equal(t, envelope1.Message, envelope2.Message)
+	assert.Equal(t, envelope1.CorrelationId, envelope2.CorrelationId)
+
+	err = c.queue.Complete(envelope2)
+	assert.Nil(t, err)
+}
+
+func (c *MessageQueueFixture) TestReceiveCompleteMessage(t *testing.T) {
+	envelope1 := cqueues.NewMessageEnvelope("123", "Test", []byte("Test message"))
+	err := c.queue.Send("", envelope1)
+	assert.Nil(t, err)
+
+	count, err := c.queue.MessageCount()
+	assert.Nil(t, err)
+	assert.True(t, count > 0)
+
+	envelope2, err := c.queue.Receive("", 10000*time.Millisecond)
+	assert.Nil(t, err)
+	assert.NotNil(t, envelope2)
+	assert.Equal(t, envelope1.MessageType, envelope2.MessageType)
+	assert.Equal(t, envelope1.Message, envelope2.Message)
+	assert.Equal(t, envelope1.CorrelationId, envelope2.CorrelationId)
+
+	err = c.queue.Complete(envelope2)
+	assert.Nil(t, err)
+	assert.Nil(t, envelope2.GetReference())
+
+	count, err = c.queue.MessageCount()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), count)
+}
+
+func (c *MessageQueueFixture) TestReceiveAbandonMessage(t *testing.T) {
+	envelope1 := cqueues.NewMessageEnvelope("123", "Test", []byte("Test message"))
+	err := c.queue.Send("", envelope1)
+	assert.Nil(t, err)
+
+	envelope2, err := c.queue.Receive("", 10000*time.Millisecond)
+	assert.Nil(t, err)
+	assert.NotNil(t, envelope2)
+
+	err = c.queue.Abandon(envelope2)
+	assert.Nil(t, err)
+
+	envelope2, err = c.queue.Receive("", 10000*time.Millisecond)
+	assert.Nil(t, err)
+	assert.NotNil(t, envelope2)
+	assert.Equal(t, envelope1.MessageType, envelope2.MessageType)
+	assert.Equal(t, envelope1.Message, envelope2.Message)
+	assert.Equal(t, envelope1.CorrelationId, envelope2.CorrelationId)
+
+	err = c.queue.Complete(envelope2)
+	assert.Nil(t, err)
+}
+
+func (c *MessageQueueFixture) TestSendPeekMessage(t *testing.T) {
+	envelope1 := cqueues.NewMessageEnvelope("123", "Test", []byte("Test message"))
+	err := c.queue.Send("", envelope1)
+	assert.Nil(t, err)
+
+	envelope2, err := c.queue.Peek("")
+	assert.Nil(t, err)
+	assert.NotNil(t, envelope2)
+	assert.Equal(t, envelope1.MessageType, envelope2.MessageType)
+	assert.Equal(t, envelope1.Message, envelope2.Message)
+	assert.Equal(t, envelope1.CorrelationId, envelope2.CorrelationId)
+
+	// Peeked message is still in the queue
+	envelope2, err = c.queue.Receive("", 10000*time.Millisecond)
+	assert.Nil(t, err)
+	assert.NotNil(t, envelope2)
+
+	err = c.queue.Complete(envelope2)
+	assert.Nil(t, err)
+}
+
+func (c *MessageQueueFixture) TestPeekNoMessage(t *testing.T) {
+	envelope, err := c.queue.Peek("")
+	assert.Nil(t, err)
+	assert.Nil(t, envelope)
+}
+
+func (c *MessageQueueFixture) TestMoveToDeadMessage(t *testing.T) {
+	envelope1 := cqueues.NewMessageEnvelope("123", "Test", []byte("Test message"))
+	err := c.queue.Send("", envelope1)
+	assert.Nil(t, err)
+
+	envelope2, err := c.queue.Receive("", 10000*time.Millisecond)
+	assert.Nil(t, err)
+	assert.NotNil(t, envelope2)
+
+	err = c.queue.MoveToDeadLetter(envelope2)
+	assert.Nil(t, err)
+
+	count, err := c.queue.MessageCount()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), count)
+}
+
+func (c *MessageQueueFixture) TestOnMessage(t *testing.T) {
+	envelope1 := cqueues.NewMessageEnvelope("123", "Test", []byte("Test message"))
+	receiver := &testMessageReceiver{messages: make(chan *cqueues.MessageEnvelope, 1)}
+	c.queue.BeginListen("", receiver)
+	defer c.queue.EndListen("")
+
+	time.Sleep(500 * time.Millisecond)
+
+	err := c.queue.Send("", envelope1)
+	assert.Nil(t, err)
+
+	select {
+	case envelope2 := <-receiver.messages:
+		assert.Equal(t, envelope1.MessageType, envelope2.MessageType)
+		assert.Equal(t, envelope1.Message, envelope2.Message)
+		assert.Equal(t, envelope1.CorrelationId, envelope2.CorrelationId)
+	case <-time.After(5000 * time.Millisecond):
+		assert.Fail(t, "Message was not received")
+	}
+}
+
+type testMessageReceiver struct {
+	messages chan *cqueues.MessageEnvelope
+}
+
+func (c *testMessageReceiver) ReceiveMessage(envelope *cqueues.MessageEnvelope, queue cqueues.IMessageQueue) error {
+	c.messages <- envelope
+	return queue.Complete(envelope)
+}
@@ -0,0 +1,82 @@
+package test_lock
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	redislock "github.com/pip-services3-go/pip-services3-redis-go/lock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisLeaderElection(t *testing.T) {
+	host := os.Getenv("REDIS_SERVICE_HOST")
+	if host == "" {
+		host = "localhost"
+	}
+
+	port := os.Getenv("REDIS_SERVICE_PORT")
+	if port == "" {
+		port = "6379"
+	}
+
+	newCandidate := func(candidateId string) *redislock.RedisLeaderElection {
+		election := redislock.NewRedisLeaderElection()
+		election.Configure(cconf.NewConfigParamsFromTuples(
+			"connection.host", host,
+			"connection.port", port,
+			"options.group", "test_election",
+			"options.candidate_id", candidateId,
+			"options.lease_timeout", 1000,
+			"options.retry_timeout", 100,
+		))
+		return election
+	}
+
+	t.Run("Elect Single Leader", func(t *testing.T) {
+		elected := make(chan string, 2)
+		revoked := make(chan string, 2)
+
+		candidate1 := newCandidate("candidate_1")
+		candidate2 := newCandidate("candidate_2")
+		for _, candidate := range []*redislock.RedisLeaderElection{candidate1, candidate2} {
+			candidate.SetOnElected(func(group string, candidateId string) {
+				elected <- candidateId
+			})
+			candidate.SetOnRevoked(func(group string, candidateId string) {
+				revoked <- candidateId
+			})
+		}
+
+		err := candidate1.Open("")
+		assert.Nil(t, err)
+		assert.Equal(t, "candidate_1", <-elected)
+
+		err = candidate2.Open("")
+		assert.Nil(t, err)
+		defer candidate2.Close("")
+
+		// The lease is renewed, so the leader does not change
+		time.Sleep(1500 * time.Millisecond)
+		assert.True(t, candidate1.IsLeader())
+		assert.False(t, candidate2.IsLeader())
+
+		leaderId, err := candidate2.GetLeaderId("")
+		assert.Nil(t, err)
+		assert.Equal(t, "candidate_1", leaderId)
+
+		// The leader steps down on close and another candidate takes over
+		err = candidate1.Close("")
+		assert.Nil(t, err)
+		assert.Equal(t, "candidate_1", <-revoked)
+
+		select {
+		case candidateId := <-elected:
+			assert.Equal(t, "candidate_2", candidateId)
+		case <-time.After(time.Second):
+			assert.Fail(t, "Leadership was not taken over")
+		}
+		assert.True(t, candidate2.IsLeader())
+	})
+}
@@ -2,7 +2,11 @@ package test_lock
 
 import (
 	"os"
+	"strconv"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
 
 	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
 	redislock "github.com/pip-services3-go/pip-services3-redis-go/lock"
@@ -38,4 +42,406 @@ func TestRedisLock(t *testing.T) {
 	t.Run("Try Acquire Lock", fixture.TestTryAcquireLock)
 	t.Run("Acquire Lock", fixture.TestAcquireLock)
 	t.Run("Release Lock", fixture.TestReleaseLock)
+	t.Run("Release Lock With Token", func(t *testing.T) {
+		testReleaseLockWithToken(t, lock)
+	})
+	t.Run("Extend Lock", func(t *testing.T) {
+		testExtendLock(t, lock)
+	})
+	t.Run("Reentrant Lock", func(t *testing.T) {
+		testReentrantLock(t, lock)
+	})
+	t.Run("Wake Up On Release", func(t *testing.T) {
+		testWakeUpOnRelease(t, lock)
+	})
+	t.Run("Inspect Locks", func(t *testing.T) {
+		testInspectLocks(t, lock)
+	})
+	t.Run("Force Release Lock", func(t *testing.T) {
+		testForceReleaseLock(t, lock)
+	})
+	t.Run("Fencing Tokens", func(t *testing.T) {
+		testFencingTokens(t, lock)
+	})
+	t.Run("Acquire Several Locks", func(t *testing.T) {
+		testAcquireLocks(t, lock)
+	})
+}
+
+func testReleaseLockWithToken(t *testing.T, lock *redislock.RedisLock) {
+	token1, err := lock.TryAcquireLockWithToken("", "lock_token", 3000)
+	assert.Nil(t, err)
+	assert.NotEqual(t, "", token1)
+
+	// Lock is held by the first token
+	token2, err := lock.TryAcquireLockWithToken("", "lock_token", 3000)
+	assert.Nil(t, err)
+	assert.Equal(t, "", token2)
+
+	// Release with a foreign token shall not remove the lock
+	err = lock.ReleaseLockWithToken("", "lock_token", "foreign")
+	assert.Nil(t, err)
+
+	token2, err = lock.TryAcquireLockWithToken("", "lock_token", 3000)
+	assert.Nil(t, err)
+	assert.Equal(t, "", token2)
+
+	// Release with the owner token
+	err = lock.ReleaseLockWithToken("", "lock_token", token1)
+	assert.Nil(t, err)
+
+	token2, err = lock.TryAcquireLockWithToken("", "lock_token", 3000)
+	assert.Nil(t, err)
+	assert.NotEqual(t, "", token2)
+	assert.NotEqual(t, token1, token2)
+
+	lock.ReleaseLockWithToken("", "lock_token", token2)
+}
+
+func testExtendLock(t *testing.T, lock *redislock.RedisLock) {
+	result, err := lock.TryAcquireLock("", "lock_extend", 1000)
+	assert.Nil(t, err)
+	assert.True(t, result)
+
+	result, err = lock.ExtendLock("", "lock_extend", 3000)
+	assert.Nil(t, err)
+	assert.True(t, result)
+
+	// The lock shall still be held after the original ttl
+	time.Sleep(1500 * time.Millisecond)
+
+	token, err := lock.TryAcquireLockWithToken("", "lock_extend", 3000)
+	assert.Nil(t, err)
+	assert.Equal(t, "", token)
+
+	// Foreign tokens cannot extend the lock
+	result, err = lock.ExtendLockWithToken("", "lock_extend", "foreign", 3000)
+	assert.Nil(t, err)
+	assert.False(t, result)
+
+	lock.ReleaseLock("", "lock_extend")
+
+	result, err = lock.ExtendLock("", "lock_extend", 3000)
+	assert.Nil(t, err)
+	assert.False(t, result)
+}
+
+func testReentrantLock(t *testing.T, lock *redislock.RedisLock) {
+	token, err := lock.TryAcquireLockWithToken("", "lock_reentrant", 3000)
+	assert.Nil(t, err)
+	assert.NotEqual(t, "", token)
+
+	// The same owner can acquire the lock again
+	result, err := lock.TryAcquireLockForOwner("", "lock_reentrant", token, 3000)
+	assert.Nil(t, err)
+	assert.True(t, result)
+
+	// Other owners cannot
+	result, err = lock.TryAcquireLockForOwner("", "lock_reentrant", "foreign", 3000)
+	assert.Nil(t, err)
+	assert.False(t, result)
+
+	// The first release keeps the lock held
+	err = lock.ReleaseLockWithToken("", "lock_reentrant", token)
+	assert.Nil(t, err)
+
+	result, err = lock.TryAcquireLockForOwner("", "lock_reentrant", "foreign", 3000)
+	assert.Nil(t, err)
+	assert.False(t, result)
+
+	// The second release frees the lock
+	err = lock.ReleaseLockWithToken("", "lock_reentrant", token)
+	assert.Nil(t, err)
+
+	result, err = lock.TryAcquireLockForOwner("", "lock_reentrant", "foreign", 3000)
+	assert.Nil(t, err)
+	assert.True(t, result)
+
+	lock.ReleaseLockWithToken("", "lock_reentrant", "foreign")
+}
+
+func testWakeUpOnRelease(t *testing.T, lock *redislock.RedisLock) {
+	holder, err := lock.TryAcquireLockWithToken("", "lock_notify", 10000)
+	assert.Nil(t, err)
+	assert.NotEqual(t, "", holder)
+
+	acquired := make(chan time.Time)
+	go func() {
+		token, err := lock.AcquireLockWithToken("", "lock_notify", 3000, 5000)
+		if err == nil {
+			acquired <- time.Now()
+			lock.ReleaseLockWithToken("", "lock_notify", token)
+		}
+	}()
+
+	time.Sleep(200 * time.Millisecond)
+	releasedAt := time.Now()
+	lock.ReleaseLockWithToken("", "lock_notify", holder)
+
+	// The waiter is woken up long before the lock would expire
+	acquiredAt := <-acquired
+	assert.True(t, acquiredAt.Sub(releasedAt) < time.Second)
+}
+
+func testInspectLocks(t *testing.T, lock *redislock.RedisLock) {
+	token, err := lock.TryAcquireLockWithToken("123", "inspect:lock_1", 3000)
+	assert.Nil(t, err)
+	assert.NotEqual(t, "", token)
+
+	result, err := lock.IsLocked("", "inspect:lock_1")
+	assert.Nil(t, err)
+	assert.True(t, result)
+
+	info, err := lock.GetLockInfo("", "inspect:lock_1")
+	assert.Nil(t, err)
+	assert.NotNil(t, info)
+	assert.Equal(t, token, info.Owner)
+	assert.Equal(t, 1, info.Count)
+	assert.True(t, info.Ttl > 0 && info.Ttl <= 3000)
+
+	// Owner metadata is stored with the lock
+	hostname, _ := os.Hostname()
+	assert.Equal(t, hostname, info.Metadata["host"])
+	assert.Equal(t, strconv.Itoa(os.Getpid()), info.Metadata["pid"])
+	assert.Equal(t, "123", info.Metadata["correlation_id"])
+	assert.NotEqual(t, "", info.Metadata["acquired_at"])
+
+	locks, err := lock.ListLocks("", "inspect:*")
+	assert.Nil(t, err)
+	assert.Len(t, locks, 1)
+	assert.Equal(t, "inspect:lock_1", locks[0].Key)
+
+	lock.ReleaseLockWithToken("", "inspect:lock_1", token)
+
+	result, err = lock.IsLocked("", "inspect:lock_1")
+	assert.Nil(t, err)
+	assert.False(t, result)
+
+	info, err = lock.GetLockInfo("", "inspect:lock_1")
+	assert.Nil(t, err)
+	assert.Nil(t, info)
+}
+
+func testFencingTokens(t *testing.T, lock *redislock.RedisLock) {
+	token1, fence1, err := lock.TryAcquireLockWithFencing("", "lock_fence", 3000)
+	assert.Nil(t, err)
+	assert.NotEqual(t, "", token1)
+	assert.True(t, fence1 > 0)
+
+	// Reentrant acquisition keeps the fencing token
+	result, err := lock.TryAcquireLockForOwner("", "lock_fence", token1, 3000)
+	assert.Nil(t, err)
+	assert.True(t, result)
+
+	info, err := lock.GetLockInfo("", "lock_fence")
+	assert.Nil(t, err)
+	assert.Equal(t, fence1, info.Fence)
+
+	// Lock held by someone else returns no fencing token
+	token, fence, err := lock.TryAcquireLockWithFencing("", "lock_fence", 3000)
+	assert.Nil(t, err)
+	assert.Equal(t, "", token)
+	assert.Equal(t, int64(0), fence)
+
+	lock.ReleaseLockWithToken("", "lock_fence", token1)
+	lock.ReleaseLockWithToken("", "lock_fence", token1)
+
+	token2, fence2, err := lock.AcquireLockWithFencing("", "lock_fence", 3000, 1000)
+	assert.Nil(t, err)
+	assert.True(t, fence2 > fence1)
+
+	// Fencing tokens keep growing after the lock expires
+	time.Sleep(3500 * time.Millisecond)
+
+	token3, fence3, err := lock.TryAcquireLockWithFencing("", "lock_fence", 3000)
+	assert.Nil(t, err)
+	assert.True(t, fence3 > fence2)
+
+	lock.ReleaseLockWithToken("", "lock_fence", token2)
+	lock.ReleaseLockWithToken("", "lock_fence", token3)
+}
+
+func testAcquireLocks(t *testing.T, lock *redislock.RedisLock) {
+	keys := []string{"account_1", "account_2"}
+
+	token1, err := lock.TryAcquireLocks("", keys, 3000)
+	assert.Nil(t, err)
+	assert.NotEqual(t, "", token1)
+
+	result, err := lock.IsLocked("", "account_2")
+	assert.Nil(t, err)
+	assert.True(t, result)
+
+	// None of the locks is acquired when one of them is held
+	token2, err := lock.TryAcquireLocks("", []string{"account_3", "account_2"}, 3000)
+	assert.Nil(t, err)
+	assert.Equal(t, "", token2)
+
+	result, err = lock.IsLocked("", "account_3")
+	assert.Nil(t, err)
+	assert.False(t, result)
+
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		lock.ReleaseLocks("", keys, token1)
+	}()
+
+	// Keys in a different order are acquired after the group is released
+	token2, err = lock.AcquireLocks("", []string{"account_3", "account_2", "account_1"}, 3000, 1000)
+	assert.Nil(t, err)
+	assert.NotEqual(t, "", token2)
+
+	err = lock.ReleaseLocks("", []string{"account_1", "account_2", "account_3"}, token2)
+	assert.Nil(t, err)
+
+	for _, key := range []string{"account_1", "account_2", "account_3"} {
+		result, err = lock.IsLocked("", key)
+		assert.Nil(t, err)
+		assert.False(t, result)
+	}
+}
+
+func testForceReleaseLock(t *testing.T, lock *redislock.RedisLock) {
+	lost := make(chan string, 1)
+	lock.SetLockLostCallback(func(key string, token string, reason string) {
+		lost <- reason
+	})
+	defer lock.SetLockLostCallback(nil)
+
+	result, err := lock.TryAcquireLock("", "lock_force", 10000)
+	assert.Nil(t, err)
+	assert.True(t, result)
+
+	err = lock.ForceReleaseLock("123", "lock_force", "Hung job")
+	assert.Nil(t, err)
+
+	result, err = lock.IsLocked("", "lock_force")
+	assert.Nil(t, err)
+	assert.False(t, result)
+
+	// The previous owner is notified
+	select {
+	case reason := <-lost:
+		assert.Equal(t, "Hung job", reason)
+	case <-time.After(time.Second):
+		assert.Fail(t, "Lock lost notification was not received")
+	}
+
+	// The break is recorded
+	breaks, err := lock.GetLockBreaks("", "lock_force")
+	assert.Nil(t, err)
+	assert.True(t, len(breaks) > 0)
+	assert.Equal(t, "Hung job", breaks[0].Reason)
+	assert.Equal(t, "123", breaks[0].CorrelationId)
+	assert.NotEqual(t, "", breaks[0].Owner)
+}
+
+func TestFairRedisLock(t *testing.T) {
+	host := os.Getenv("REDIS_SERVICE_HOST")
+	if host == "" {
+		host = "localhost"
+	}
+
+	port := os.Getenv("REDIS_SERVICE_PORT")
+	if port == "" {
+		port = "6379"
+	}
+
+	lock := redislock.NewRedisLock()
+
+	config := cconf.NewConfigParamsFromTuples(
+		"connection.host", host,
+		"connection.port", port,
+		"options.fair", true,
+	)
+	lock.Configure(config)
+	fixture := redisfixture.NewLockFixture(lock)
+
+	lock.Open("")
+	defer lock.Close("")
+
+	t.Run("Try Acquire Lock", fixture.TestTryAcquireLock)
+	t.Run("Acquire Lock", fixture.TestAcquireLock)
+	t.Run("Release Lock", fixture.TestReleaseLock)
+	t.Run("Acquire In Order", func(t *testing.T) {
+		holder, err := lock.TryAcquireLockWithToken("", "lock_fair", 5000)
+		assert.Nil(t, err)
+		assert.NotEqual(t, "", holder)
+
+		order := make(chan string, 2)
+		acquire := func(name string) {
+			token, err := lock.AcquireLockWithToken("", "lock_fair", 5000, 5000)
+			if err == nil {
+				order <- name
+				time.Sleep(100 * time.Millisecond)
+				lock.ReleaseLockWithToken("", "lock_fair", token)
+			}
+		}
+
+		go acquire("first")
+		time.Sleep(200 * time.Millisecond)
+		go acquire("second")
+		time.Sleep(200 * time.Millisecond)
+
+		// Single attempts cannot overtake the waiters
+		token, err := lock.TryAcquireLockWithToken("", "lock_fair", 5000)
+		assert.Nil(t, err)
+		assert.Equal(t, "", token)
+
+		// Group attempts cannot overtake the waiters either
+		token, err = lock.TryAcquireLocks("", []string{"lock_fair_group", "lock_fair"}, 5000)
+		assert.Nil(t, err)
+		assert.Equal(t, "", token)
+
+		lock.ReleaseLockWithToken("", "lock_fair", holder)
+
+		assert.Equal(t, "first", <-order)
+		assert.Equal(t, "second", <-order)
+	})
+}
+
+func TestRedisLockReleaseOnClose(t *testing.T) {
+	host := os.Getenv("REDIS_SERVICE_HOST")
+	if host == "" {
+		host = "localhost"
+	}
+
+	port := os.Getenv("REDIS_SERVICE_PORT")
+	if port == "" {
+		port = "6379"
+	}
+
+	config := cconf.NewConfigParamsFromTuples(
+		"connection.host", host,
+		"connection.port", port,
+		"options.debug", true,
+	)
+
+	lock := redislock.NewRedisLock()
+	lock.Configure(config)
+	lock.Open("")
+
+	checker := redislock.NewRedisLock()
+	checker.Configure(config)
+	checker.Open("")
+	defer checker.Close("")
+
+	token, err := lock.TryAcquireLockWithToken("123", "lock_close", 60000)
+	assert.Nil(t, err)
+	assert.NotEqual(t, "", token)
+
+	// Held locks are tracked together with the place that acquired them
+	held := lock.GetHeldLocks()
+	assert.Len(t, held, 1)
+	assert.Equal(t, "lock_close", held[0].Key)
+	assert.Equal(t, token, held[0].Token)
+	assert.Equal(t, "123", held[0].CorrelationId)
+	assert.Contains(t, held[0].Site, "RedisLock_test.go")
+
+	err = lock.Close("")
+	assert.Nil(t, err)
+
+	result, err := checker.IsLocked("", "lock_close")
+	assert.Nil(t, err)
+	assert.False(t, result)
 }
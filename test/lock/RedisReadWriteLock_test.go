@@ -0,0 +1,108 @@
+package test_lock
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	redislock "github.com/pip-services3-go/pip-services3-redis-go/lock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisReadWriteLock(t *testing.T) {
+	var lock *redislock.RedisReadWriteLock
+
+	host := os.Getenv("REDIS_SERVICE_HOST")
+	if host == "" {
+		host = "localhost"
+	}
+
+	port := os.Getenv("REDIS_SERVICE_PORT")
+	if port == "" {
+		port = "6379"
+	}
+
+	lock = redislock.NewRedisReadWriteLock()
+
+	config := cconf.NewConfigParamsFromTuples(
+		"connection.host", host,
+		"connection.port", port,
+	)
+	lock.Configure(config)
+
+	lock.Open("")
+	defer lock.Close("")
+
+	t.Run("Shared Readers", func(t *testing.T) {
+		reader1, err := lock.TryAcquireReadLock("", "rwlock_1", 3000)
+		assert.Nil(t, err)
+		assert.NotEqual(t, "", reader1)
+
+		// Readers do not block each other
+		reader2, err := lock.TryAcquireReadLock("", "rwlock_1", 3000)
+		assert.Nil(t, err)
+		assert.NotEqual(t, "", reader2)
+
+		// Writer is blocked by readers
+		writer, err := lock.TryAcquireWriteLock("", "rwlock_1", 3000)
+		assert.Nil(t, err)
+		assert.Equal(t, "", writer)
+
+		lock.ReleaseReadLock("", "rwlock_1", reader1)
+		lock.ReleaseReadLock("", "rwlock_1", reader2)
+
+		writer, err = lock.TryAcquireWriteLock("", "rwlock_1", 3000)
+		assert.Nil(t, err)
+		assert.NotEqual(t, "", writer)
+
+		lock.ReleaseWriteLock("", "rwlock_1", writer)
+	})
+
+	t.Run("Exclusive Writer", func(t *testing.T) {
+		writer, err := lock.TryAcquireWriteLock("", "rwlock_2", 3000)
+		assert.Nil(t, err)
+		assert.NotEqual(t, "", writer)
+
+		reader, err := lock.TryAcquireReadLock("", "rwlock_2", 3000)
+		assert.Nil(t, err)
+		assert.Equal(t, "", reader)
+
+		_, err = lock.AcquireWriteLock("", "rwlock_2", 3000, 500)
+		assert.NotNil(t, err)
+
+		err = lock.ReleaseWriteLock("", "rwlock_2", writer)
+		assert.Nil(t, err)
+
+		reader, err = lock.TryAcquireReadLock("", "rwlock_2", 3000)
+		assert.Nil(t, err)
+		assert.NotEqual(t, "", reader)
+
+		lock.ReleaseReadLock("", "rwlock_2", reader)
+	})
+
+	t.Run("Writer Priority", func(t *testing.T) {
+		reader, err := lock.TryAcquireReadLock("", "rwlock_3", 3000)
+		assert.Nil(t, err)
+		assert.NotEqual(t, "", reader)
+
+		done := make(chan string)
+		go func() {
+			writer, _ := lock.AcquireWriteLock("", "rwlock_3", 3000, 2000)
+			done <- writer
+		}()
+
+		// New readers are not admitted while the writer waits
+		time.Sleep(300 * time.Millisecond)
+		other, err := lock.TryAcquireReadLock("", "rwlock_3", 3000)
+		assert.Nil(t, err)
+		assert.Equal(t, "", other)
+
+		lock.ReleaseReadLock("", "rwlock_3", reader)
+
+		writer := <-done
+		assert.NotEqual(t, "", writer)
+
+		lock.ReleaseWriteLock("", "rwlock_3", writer)
+	})
+}
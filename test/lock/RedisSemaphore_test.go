@@ -0,0 +1,104 @@
+package test_lock
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	redislock "github.com/pip-services3-go/pip-services3-redis-go/lock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisSemaphore(t *testing.T) {
+	var semaphore *redislock.RedisSemaphore
+
+	host := os.Getenv("REDIS_SERVICE_HOST")
+	if host == "" {
+		host = "localhost"
+	}
+
+	port := os.Getenv("REDIS_SERVICE_PORT")
+	if port == "" {
+		port = "6379"
+	}
+
+	semaphore = redislock.NewRedisSemaphore()
+
+	config := cconf.NewConfigParamsFromTuples(
+		"connection.host", host,
+		"connection.port", port,
+		"permits.semaphore_1", 2,
+	)
+	semaphore.Configure(config)
+
+	semaphore.Open("")
+	defer semaphore.Close("")
+
+	t.Run("Acquire Permits", func(t *testing.T) {
+		available, err := semaphore.AvailablePermits("", "semaphore_1")
+		assert.Nil(t, err)
+		assert.Equal(t, int64(2), available)
+
+		token1, err := semaphore.TryAcquire("", "semaphore_1", 3000)
+		assert.Nil(t, err)
+		assert.NotEqual(t, "", token1)
+
+		token2, err := semaphore.TryAcquire("", "semaphore_1", 3000)
+		assert.Nil(t, err)
+		assert.NotEqual(t, "", token2)
+
+		// All permits are taken
+		token3, err := semaphore.TryAcquire("", "semaphore_1", 3000)
+		assert.Nil(t, err)
+		assert.Equal(t, "", token3)
+
+		available, err = semaphore.AvailablePermits("", "semaphore_1")
+		assert.Nil(t, err)
+		assert.Equal(t, int64(0), available)
+
+		_, err = semaphore.Acquire("", "semaphore_1", 3000, 500)
+		assert.NotNil(t, err)
+
+		err = semaphore.Release("", "semaphore_1", token1)
+		assert.Nil(t, err)
+
+		token3, err = semaphore.Acquire("", "semaphore_1", 3000, 500)
+		assert.Nil(t, err)
+		assert.NotEqual(t, "", token3)
+
+		semaphore.Release("", "semaphore_1", token2)
+		semaphore.Release("", "semaphore_1", token3)
+	})
+
+	t.Run("Expired Permits", func(t *testing.T) {
+		token, err := semaphore.TryAcquire("", "semaphore_2", 500)
+		assert.Nil(t, err)
+		assert.NotEqual(t, "", token)
+
+		available, err := semaphore.AvailablePermits("", "semaphore_2")
+		assert.Nil(t, err)
+		assert.Equal(t, int64(0), available)
+
+		// Leases of crashed holders expire on their own
+		time.Sleep(1000 * time.Millisecond)
+
+		available, err = semaphore.AvailablePermits("", "semaphore_2")
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1), available)
+	})
+}
+
+func TestRedisSemaphorePermitsConfig(t *testing.T) {
+	semaphore := redislock.NewRedisSemaphore()
+	semaphore.Configure(cconf.NewConfigParamsFromTuples(
+		"options.permits", 3,
+		"permits.semaphore_1", 2,
+		"permits.0.key", "vendor.api",
+		"permits.0.count", 5,
+	))
+
+	assert.Equal(t, 2, semaphore.GetPermits("semaphore_1"))
+	assert.Equal(t, 5, semaphore.GetPermits("vendor.api"))
+	assert.Equal(t, 3, semaphore.GetPermits("semaphore_2"))
+}
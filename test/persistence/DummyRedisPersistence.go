@@ -0,0 +1,116 @@
+package test_persistence
+
+import (
+	"reflect"
+
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	persist "github.com/pip-services3-go/pip-services3-redis-go/persistence"
+	tf "github.com/pip-services3-go/pip-services3-redis-go/test/fixture"
+)
+
+type DummyRedisPersistence struct {
+	*persist.IdentifiableRedisPersistence
+}
+
+func NewDummyRedisPersistence() *DummyRedisPersistence {
+	proto := reflect.TypeOf(tf.Dummy{})
+	c := &DummyRedisPersistence{
+		IdentifiableRedisPersistence: persist.NewIdentifiableRedisPersistence(proto, "dummies"),
+	}
+	c.EnsureIndex("key", persist.SetIndex)
+	return c
+}
+
+func (c *DummyRedisPersistence) composeQuery(filter *cdata.FilterParams) *persist.IndexQuery {
+	if filter == nil {
+		filter = cdata.NewEmptyFilterParams()
+	}
+
+	query := persist.NewIndexQuery()
+	if key := filter.GetAsString("key"); key != "" {
+		query.WithEqual("key", key)
+	}
+	return query
+}
+
+func (c *DummyRedisPersistence) GetPageByFilter(correlationId string, filter *cdata.FilterParams,
+	paging *cdata.PagingParams) (page *tf.DummyPage, err error) {
+	tempPage, err := c.IdentifiableRedisPersistence.GetPageByQuery(correlationId, c.composeQuery(filter), paging)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]tf.Dummy, len(tempPage.Data))
+	for index, item := range tempPage.Data {
+		data[index] = item.(tf.Dummy)
+	}
+	return &tf.DummyPage{Total: tempPage.Total, Data: data}, nil
+}
+
+func (c *DummyRedisPersistence) GetCountByFilter(correlationId string, filter *cdata.FilterParams) (count int64, err error) {
+	return c.IdentifiableRedisPersistence.GetCountByQuery(correlationId, c.composeQuery(filter))
+}
+
+func (c *DummyRedisPersistence) GetListByIds(correlationId string, ids []string) (items []tf.Dummy, err error) {
+	convIds := make([]interface{}, len(ids))
+	for index, id := range ids {
+		convIds[index] = id
+	}
+
+	result, err := c.IdentifiableRedisPersistence.GetListByIds(correlationId, convIds)
+	if err != nil {
+		return nil, err
+	}
+
+	items = make([]tf.Dummy, len(result))
+	for index, item := range result {
+		items[index] = item.(tf.Dummy)
+	}
+	return items, nil
+}
+
+func (c *DummyRedisPersistence) GetOneById(correlationId string, id string) (item *tf.Dummy, err error) {
+	result, err := c.IdentifiableRedisPersistence.GetOneById(correlationId, id)
+	return toDummy(result, err)
+}
+
+func (c *DummyRedisPersistence) Create(correlationId string, item tf.Dummy) (result *tf.Dummy, err error) {
+	value, err := c.IdentifiableRedisPersistence.Create(correlationId, item)
+	return toDummy(value, err)
+}
+
+func (c *DummyRedisPersistence) Update(correlationId string, item tf.Dummy) (result *tf.Dummy, err error) {
+	value, err := c.IdentifiableRedisPersistence.Update(correlationId, item)
+	return toDummy(value, err)
+}
+
+func (c *DummyRedisPersistence) Set(correlationId string, item tf.Dummy) (result *tf.Dummy, err error) {
+	value, err := c.IdentifiableRedisPersistence.Set(correlationId, item)
+	return toDummy(value, err)
+}
+
+func (c *DummyRedisPersistence) UpdatePartially(correlationId string, id string, data *cdata.AnyValueMap) (item *tf.Dummy, err error) {
+	result, err := c.IdentifiableRedisPersistence.UpdatePartially(correlationId, id, data)
+	return toDummy(result, err)
+}
+
+func (c *DummyRedisPersistence) DeleteById(correlationId string, id string) (item *tf.Dummy, err error) {
+	result, err := c.IdentifiableRedisPersistence.DeleteById(correlationId, id)
+	return toDummy(result, err)
+}
+
+func (c *DummyRedisPersistence) DeleteByIds(correlationId string, ids []string) (err error) {
+	convIds := make([]interface{}, len(ids))
+	for index, id := range ids {
+		convIds[index] = id
+	}
+	return c.IdentifiableRedisPersistence.DeleteByIds(correlationId, convIds)
+}
+
+func toDummy(value interface{}, err error) (*tf.Dummy, error) {
+	if err != nil || value == nil {
+		return nil, err
+	}
+	item := value.(tf.Dummy)
+	return &item, nil
+}
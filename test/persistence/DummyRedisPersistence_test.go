@@ -0,0 +1,39 @@
+package test_persistence
+
+import (
+	"os"
+	"testing"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	tf "github.com/pip-services3-go/pip-services3-redis-go/test/fixture"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDummyRedisPersistence(t *testing.T) {
+	host := os.Getenv("REDIS_SERVICE_HOST")
+	if host == "" {
+		host = "localhost"
+	}
+
+	port := os.Getenv("REDIS_SERVICE_PORT")
+	if port == "" {
+		port = "6379"
+	}
+
+	persistence := NewDummyRedisPersistence()
+	persistence.Configure(cconf.NewConfigParamsFromTuples(
+		"connection.host", host,
+		"connection.port", port,
+	))
+
+	err := persistence.Open("")
+	assert.Nil(t, err)
+	defer persistence.Close("")
+
+	err = persistence.Clear("")
+	assert.Nil(t, err)
+
+	fixture := tf.NewDummyPersistenceFixture(persistence)
+
+	t.Run("Crud Operations", fixture.TestCrudOperations)
+}
@@ -0,0 +1,144 @@
+package test_persistence
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	persist "github.com/pip-services3-go/pip-services3-redis-go/persistence"
+	"github.com/stretchr/testify/assert"
+)
+
+type indexedItem struct {
+	Id         string    `json:"id"`
+	Type       string    `json:"type"`
+	Tags       []string  `json:"tags"`
+	Priority   int       `json:"priority"`
+	CreateTime time.Time `json:"create_time"`
+}
+
+func TestRedisPersistenceIndexes(t *testing.T) {
+	host := os.Getenv("REDIS_SERVICE_HOST")
+	if host == "" {
+		host = "localhost"
+	}
+
+	port := os.Getenv("REDIS_SERVICE_PORT")
+	if port == "" {
+		port = "6379"
+	}
+
+	persistence := persist.NewIdentifiableRedisPersistence(reflect.TypeOf(indexedItem{}), "indexed_items")
+	persistence.EnsureIndex("type", persist.SetIndex)
+	persistence.EnsureIndex("tags", persist.SetIndex)
+	persistence.EnsureIndex("priority", persist.SortedIndex)
+	persistence.EnsureIndex("create_time", persist.SortedIndex)
+	persistence.Configure(cconf.NewConfigParamsFromTuples(
+		"connection.host", host,
+		"connection.port", port,
+	))
+
+	err := persistence.Open("")
+	assert.Nil(t, err)
+	defer persistence.Close("")
+
+	err = persistence.Clear("")
+	assert.Nil(t, err)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	items := []indexedItem{
+		{Id: "1", Type: "task", Tags: []string{"red", "blue"}, Priority: 3, CreateTime: now.Add(-3 * time.Hour)},
+		{Id: "2", Type: "task", Tags: []string{"blue"}, Priority: 1, CreateTime: now.Add(-2 * time.Hour)},
+		{Id: "3", Type: "bug", Tags: []string{"red"}, Priority: 2, CreateTime: now.Add(-1 * time.Hour)},
+		{Id: "4", Type: "task", Tags: []string{}, Priority: 5, CreateTime: now},
+	}
+	for _, item := range items {
+		_, err := persistence.Create("", item)
+		assert.Nil(t, err)
+	}
+
+	t.Run("Equal Conditions", func(t *testing.T) {
+		count, err := persistence.GetCountByQuery("", persist.NewIndexQuery().WithEqual("type", "task"))
+		assert.Nil(t, err)
+		assert.Equal(t, int64(3), count)
+
+		// Elements of arrays are indexed
+		query := persist.NewIndexQuery().WithEqual("type", "task").WithEqual("tags", "blue")
+		count, err = persistence.GetCountByQuery("", query)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(2), count)
+	})
+
+	t.Run("Sorted Paging", func(t *testing.T) {
+		query := persist.NewIndexQuery().WithEqual("type", "task").WithSort("priority", true)
+		page, err := persistence.GetPageByQuery("", query, cdata.NewPagingParams(1, 1, true))
+		assert.Nil(t, err)
+		assert.Equal(t, int64(3), *page.Total)
+		assert.Len(t, page.Data, 1)
+		assert.Equal(t, "1", page.Data[0].(indexedItem).Id)
+	})
+
+	t.Run("Range Conditions", func(t *testing.T) {
+		query := persist.NewIndexQuery().WithRange("priority", 2, 3).WithSort("priority", false)
+		page, err := persistence.GetPageByQuery("", query, nil)
+		assert.Nil(t, err)
+		assert.Len(t, page.Data, 2)
+		assert.Equal(t, "3", page.Data[0].(indexedItem).Id)
+		assert.Equal(t, "1", page.Data[1].(indexedItem).Id)
+
+		query = persist.NewIndexQuery().WithRange("create_time", now.Add(-90*time.Minute), nil)
+		count, err := persistence.GetCountByQuery("", query)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(2), count)
+
+		// Equal and range conditions are combined
+		query = persist.NewIndexQuery().WithEqual("type", "task").WithRange("create_time", now.Add(-150*time.Minute), nil)
+		count, err = persistence.GetCountByQuery("", query)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(2), count)
+
+		// Bounds that can't be compared are rejected instead of being ignored
+		query = persist.NewIndexQuery().WithRange("priority", "high", nil)
+		_, err = persistence.GetCountByQuery("", query)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("Index Updates", func(t *testing.T) {
+		_, err := persistence.UpdatePartially("", "2", cdata.NewAnyValueMapFromTuples("type", "bug", "priority", 10))
+		assert.Nil(t, err)
+
+		count, err := persistence.GetCountByQuery("", persist.NewIndexQuery().WithEqual("type", "task"))
+		assert.Nil(t, err)
+		assert.Equal(t, int64(2), count)
+
+		query := persist.NewIndexQuery().WithSort("priority", true)
+		page, err := persistence.GetPageByQuery("", query, cdata.NewPagingParams(0, 1, false))
+		assert.Nil(t, err)
+		assert.Nil(t, page.Total)
+		assert.Equal(t, "2", page.Data[0].(indexedItem).Id)
+
+		_, err = persistence.DeleteById("", "3")
+		assert.Nil(t, err)
+
+		count, err = persistence.GetCountByQuery("", persist.NewIndexQuery().WithEqual("tags", "red"))
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("Rebuild Indexes", func(t *testing.T) {
+		err := persistence.RebuildIndexes("")
+		assert.Nil(t, err)
+
+		count, err := persistence.GetCountByQuery("", persist.NewIndexQuery().WithEqual("type", "bug"))
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("Missing Index", func(t *testing.T) {
+		_, err := persistence.GetCountByQuery("", persist.NewIndexQuery().WithEqual("priority", 1))
+		assert.NotNil(t, err)
+	})
+}
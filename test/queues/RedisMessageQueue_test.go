@@ -0,0 +1,77 @@
+package test_queues
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	redisqueues "github.com/pip-services3-go/pip-services3-redis-go/queues"
+	redisfixture "github.com/pip-services3-go/pip-services3-redis-go/test/fixture"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisMessageQueue(t *testing.T) {
+	host := os.Getenv("REDIS_SERVICE_HOST")
+	if host == "" {
+		host = "localhost"
+	}
+
+	port := os.Getenv("REDIS_SERVICE_PORT")
+	if port == "" {
+		port = "6379"
+	}
+
+	queue := redisqueues.NewRedisMessageQueue("TestQueue")
+	queue.Configure(cconf.NewConfigParamsFromTuples(
+		"connection.host", host,
+		"connection.port", port,
+		"options.lock_timeout", 1000,
+	))
+	fixture := redisfixture.NewMessageQueueFixture(queue)
+
+	err := queue.Open("")
+	assert.Nil(t, err)
+	defer queue.Close("")
+
+	queue.Clear("")
+
+	t.Run("Send Receive Message", fixture.TestSendReceiveMessage)
+	queue.Clear("")
+	t.Run("Receive Send Message", fixture.TestReceiveSendMessage)
+	queue.Clear("")
+	t.Run("Receive Complete Message", fixture.TestReceiveCompleteMessage)
+	queue.Clear("")
+	t.Run("Receive Abandon Message", fixture.TestReceiveAbandonMessage)
+	queue.Clear("")
+	t.Run("Send Peek Message", fixture.TestSendPeekMessage)
+	queue.Clear("")
+	t.Run("Peek No Message", fixture.TestPeekNoMessage)
+	queue.Clear("")
+	t.Run("Move To Dead Message", fixture.TestMoveToDeadMessage)
+	queue.Clear("")
+	t.Run("On Message", fixture.TestOnMessage)
+	queue.Clear("")
+
+	t.Run("Redeliver Expired Message", func(t *testing.T) {
+		err := queue.SendAsObject("123", "Test", map[string]string{"key": "value"})
+		assert.Nil(t, err)
+
+		envelope1, err := queue.Receive("", 1000*time.Millisecond)
+		assert.Nil(t, err)
+		assert.NotNil(t, envelope1)
+
+		// The message is invisible until its lock expires
+		envelope2, err := queue.Receive("", 0)
+		assert.Nil(t, err)
+		assert.Nil(t, envelope2)
+
+		envelope2, err = queue.Receive("", 3000*time.Millisecond)
+		assert.Nil(t, err)
+		assert.NotNil(t, envelope2)
+		assert.Equal(t, envelope1.MessageId, envelope2.MessageId)
+
+		err = queue.Complete(envelope2)
+		assert.Nil(t, err)
+	})
+}
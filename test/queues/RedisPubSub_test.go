@@ -0,0 +1,85 @@
+package test_queues
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cqueues "github.com/pip-services3-go/pip-services3-messaging-go/queues"
+	redisqueues "github.com/pip-services3-go/pip-services3-redis-go/queues"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisPubSub(t *testing.T) {
+	host := os.Getenv("REDIS_SERVICE_HOST")
+	if host == "" {
+		host = "localhost"
+	}
+
+	port := os.Getenv("REDIS_SERVICE_PORT")
+	if port == "" {
+		port = "6379"
+	}
+
+	bus := redisqueues.NewRedisPubSub()
+	bus.Configure(cconf.NewConfigParamsFromTuples(
+		"connection.host", host,
+		"connection.port", port,
+	))
+
+	// Subscriptions made before opening are restored on open
+	messages := make(chan *cqueues.MessageEnvelope, 10)
+	err := bus.Subscribe("", "test:config", func(channel string, envelope *cqueues.MessageEnvelope) {
+		messages <- envelope
+	})
+	assert.Nil(t, err)
+
+	err = bus.Open("")
+	assert.Nil(t, err)
+	defer bus.Close("")
+
+	t.Run("Publish Subscribe", func(t *testing.T) {
+		time.Sleep(200 * time.Millisecond)
+
+		err := bus.PublishAsObject("123", "test:config", "changed", map[string]string{"key": "value"})
+		assert.Nil(t, err)
+
+		select {
+		case envelope := <-messages:
+			assert.Equal(t, "123", envelope.CorrelationId)
+			assert.Equal(t, "changed", envelope.MessageType)
+
+			var message map[string]string
+			err = json.Unmarshal(envelope.Message, &message)
+			assert.Nil(t, err)
+			assert.Equal(t, "value", message["key"])
+		case <-time.After(3000 * time.Millisecond):
+			assert.Fail(t, "Message was not received")
+		}
+	})
+
+	t.Run("Pattern Subscribe", func(t *testing.T) {
+		channels := make(chan string, 10)
+		err := bus.PSubscribe("", "test:cache:*", func(channel string, envelope *cqueues.MessageEnvelope) {
+			channels <- channel
+		})
+		assert.Nil(t, err)
+
+		time.Sleep(200 * time.Millisecond)
+
+		err = bus.PublishAsObject("123", "test:cache:users", "invalidate", "user_1")
+		assert.Nil(t, err)
+
+		select {
+		case channel := <-channels:
+			assert.Equal(t, "test:cache:users", channel)
+		case <-time.After(3000 * time.Millisecond):
+			assert.Fail(t, "Message was not received")
+		}
+
+		err = bus.PUnsubscribe("", "test:cache:*")
+		assert.Nil(t, err)
+	})
+}
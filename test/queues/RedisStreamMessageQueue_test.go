@@ -0,0 +1,119 @@
+package test_queues
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	redisqueues "github.com/pip-services3-go/pip-services3-redis-go/queues"
+	redisfixture "github.com/pip-services3-go/pip-services3-redis-go/test/fixture"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisStreamMessageQueue(t *testing.T) {
+	host := os.Getenv("REDIS_SERVICE_HOST")
+	if host == "" {
+		host = "localhost"
+	}
+
+	port := os.Getenv("REDIS_SERVICE_PORT")
+	if port == "" {
+		port = "6379"
+	}
+
+	config := cconf.NewConfigParamsFromTuples(
+		"connection.host", host,
+		"connection.port", port,
+		"options.claim_timeout", 500,
+		"options.max_deliveries", 2,
+	)
+
+	queue := redisqueues.NewRedisStreamMessageQueue("TestStream")
+	queue.Configure(config)
+	fixture := redisfixture.NewMessageQueueFixture(queue)
+
+	err := queue.Open("")
+	assert.Nil(t, err)
+	defer queue.Close("")
+
+	queue.Clear("")
+
+	t.Run("Send Receive Message", fixture.TestSendReceiveMessage)
+	queue.Clear("")
+	t.Run("Receive Send Message", fixture.TestReceiveSendMessage)
+	queue.Clear("")
+	t.Run("Receive Complete Message", fixture.TestReceiveCompleteMessage)
+	queue.Clear("")
+	t.Run("Receive Abandon Message", fixture.TestReceiveAbandonMessage)
+	queue.Clear("")
+	t.Run("Send Peek Message", fixture.TestSendPeekMessage)
+	queue.Clear("")
+	t.Run("Peek No Message", fixture.TestPeekNoMessage)
+	queue.Clear("")
+	t.Run("Move To Dead Message", fixture.TestMoveToDeadMessage)
+	queue.Clear("")
+	t.Run("On Message", fixture.TestOnMessage)
+	queue.Clear("")
+
+	t.Run("Claim And Dead Letter Message", func(t *testing.T) {
+		// Another consumer of the same group receives the message and dies
+		consumer := redisqueues.NewRedisStreamMessageQueue("TestStream")
+		consumer.Configure(config)
+		err := consumer.Open("")
+		assert.Nil(t, err)
+
+		err = queue.SendAsObject("123", "Test", map[string]string{"key": "value"})
+		assert.Nil(t, err)
+
+		envelope1, err := consumer.Receive("", 1000*time.Millisecond)
+		assert.Nil(t, err)
+		assert.NotNil(t, envelope1)
+		consumer.Close("")
+
+		// The pending message is claimed after the claim timeout
+		envelope2, err := queue.Receive("", 0)
+		assert.Nil(t, err)
+		assert.Nil(t, envelope2)
+
+		time.Sleep(600 * time.Millisecond)
+
+		envelope2, err = queue.Receive("", 0)
+		assert.Nil(t, err)
+		assert.NotNil(t, envelope2)
+		assert.Equal(t, envelope1.MessageId, envelope2.MessageId)
+
+		// The message is delivered too many times and goes to the dead letters
+		time.Sleep(600 * time.Millisecond)
+
+		envelope2, err = queue.Receive("", 0)
+		assert.Nil(t, err)
+		assert.Nil(t, envelope2)
+
+		count, err := queue.ReadDeadLetterCount()
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("Fan Out To Groups", func(t *testing.T) {
+		other := redisqueues.NewRedisStreamMessageQueue("TestStream")
+		other.Configure(config.Override(cconf.NewConfigParamsFromTuples("options.group", "other")))
+		err := other.Open("")
+		assert.Nil(t, err)
+		defer other.Close("")
+
+		err = queue.SendAsObject("123", "Test", "ABC")
+		assert.Nil(t, err)
+
+		envelope1, err := queue.Receive("", 1000*time.Millisecond)
+		assert.Nil(t, err)
+		assert.NotNil(t, envelope1)
+		queue.Complete(envelope1)
+
+		envelope2, err := other.Receive("", 1000*time.Millisecond)
+		assert.Nil(t, err)
+		assert.NotNil(t, envelope2)
+		assert.Equal(t, envelope1.MessageId, envelope2.MessageId)
+		other.Complete(envelope2)
+	})
+}
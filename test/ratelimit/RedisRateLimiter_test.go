@@ -0,0 +1,71 @@
+package test_ratelimit
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	cconf "github.com/pip-services3-go/pip-services3-commons-go/config"
+	cdata "github.com/pip-services3-go/pip-services3-commons-go/data"
+	redisratelimit "github.com/pip-services3-go/pip-services3-redis-go/ratelimit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisRateLimiter(t *testing.T) {
+	host := os.Getenv("REDIS_SERVICE_HOST")
+	if host == "" {
+		host = "localhost"
+	}
+
+	port := os.Getenv("REDIS_SERVICE_PORT")
+	if port == "" {
+		port = "6379"
+	}
+
+	algorithms := []string{redisratelimit.FixedWindow, redisratelimit.SlidingWindow, redisratelimit.TokenBucket}
+	for _, algorithm := range algorithms {
+		t.Run(algorithm, func(t *testing.T) {
+			limiter := redisratelimit.NewRedisRateLimiter()
+			limiter.Configure(cconf.NewConfigParamsFromTuples(
+				"connection.host", host,
+				"connection.port", port,
+				"options.algorithm", algorithm,
+				"options.limit", 3,
+				"options.interval", 1000,
+			))
+
+			err := limiter.Open("")
+			assert.Nil(t, err)
+			defer limiter.Close("")
+
+			key := "tenant_" + cdata.IdGenerator.NextLong()
+
+			result, err := limiter.Allow("", key, 2)
+			assert.Nil(t, err)
+			assert.True(t, result.Allowed)
+			assert.Equal(t, int64(1), result.Remaining)
+
+			result, err = limiter.Allow("", key, 1)
+			assert.Nil(t, err)
+			assert.True(t, result.Allowed)
+			assert.Equal(t, int64(0), result.Remaining)
+
+			// The limit is exhausted
+			result, err = limiter.Allow("", key, 1)
+			assert.Nil(t, err)
+			assert.False(t, result.Allowed)
+			assert.True(t, result.RetryAfter > 0)
+			assert.True(t, result.RetryAfter <= 1000)
+
+			// The limit is restored after the interval
+			time.Sleep(1100 * time.Millisecond)
+
+			result, err = limiter.Allow("", key, 1)
+			assert.Nil(t, err)
+			assert.True(t, result.Allowed)
+
+			_, err = limiter.Allow("", key, 4)
+			assert.NotNil(t, err)
+		})
+	}
+}